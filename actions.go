@@ -1,22 +1,26 @@
 package isoautomate
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"time"
-
-	"github.com/google/uuid"
 )
 
 // --- File & Screenshot Actions ---
 
-func (c *Client) Screenshot(filename string, selector string) (map[string]interface{}, error) {
+func (s *BrowserSession) Screenshot(filename string, selector string) (map[string]interface{}, error) {
+	return s.ScreenshotContext(context.Background(), filename, selector)
+}
+
+func (s *BrowserSession) ScreenshotContext(ctx context.Context, filename string, selector string) (map[string]interface{}, error) {
 	if filename == "" {
 		timestamp := time.Now().Format("20060102_150405")
-		uniqueID := uuid.New().Hex()[:4]
+		uniqueID := newHexID()[:4]
 		filename = filepath.Join(ScreenshotFolder, fmt.Sprintf("%s_%s.png", timestamp, uniqueID))
 	}
 
@@ -25,31 +29,39 @@ func (c *Client) Screenshot(filename string, selector string) (map[string]interf
 		args["selector"] = selector
 	}
 
-	res, err := c.Send("save_screenshot", args)
+	res, err := s.SendContext(ctx, "save_screenshot", args)
 	if err != nil {
-		return nil, err
+		return res, err
 	}
-	return c.saveBase64Result(res, "image_base64", filename)
+	return s.saveBase64Result(res, "image_base64", filename)
 }
 
-func (c *Client) SaveAsPDF(filename string) (map[string]interface{}, error) {
+func (s *BrowserSession) SaveAsPDF(filename string) (map[string]interface{}, error) {
+	return s.SaveAsPDFContext(context.Background(), filename)
+}
+
+func (s *BrowserSession) SaveAsPDFContext(ctx context.Context, filename string) (map[string]interface{}, error) {
 	if filename == "" {
 		filename = fmt.Sprintf("doc_%d.pdf", time.Now().Unix())
 	}
-	res, err := c.Send("save_as_pdf", nil)
+	res, err := s.SendContext(ctx, "save_as_pdf", nil)
 	if err != nil {
-		return nil, err
+		return res, err
 	}
-	return c.saveBase64Result(res, "pdf_base64", filename)
+	return s.saveBase64Result(res, "pdf_base64", filename)
 }
 
-func (c *Client) SavePageSource(name string) (map[string]interface{}, error) {
+func (s *BrowserSession) SavePageSource(name string) (map[string]interface{}, error) {
+	return s.SavePageSourceContext(context.Background(), name)
+}
+
+func (s *BrowserSession) SavePageSourceContext(ctx context.Context, name string) (map[string]interface{}, error) {
 	if name == "" {
 		name = "source.html"
 	}
-	res, err := c.Send("save_page_source", nil)
+	res, err := s.SendContext(ctx, "save_page_source", nil)
 	if err != nil {
-		return nil, err
+		return res, err
 	}
 
 	// Python logic: decode source_base64 and write text
@@ -69,14 +81,22 @@ func (c *Client) SavePageSource(name string) (map[string]interface{}, error) {
 	return res, nil
 }
 
-func (c *Client) ExecuteCDPCmd(cmd string, params map[string]interface{}) (map[string]interface{}, error) {
-	return c.Send("execute_cdp_cmd", map[string]interface{}{
+func (s *BrowserSession) ExecuteCDPCmd(cmd string, params map[string]interface{}) (map[string]interface{}, error) {
+	return s.ExecuteCDPCmdContext(context.Background(), cmd, params)
+}
+
+func (s *BrowserSession) ExecuteCDPCmdContext(ctx context.Context, cmd string, params map[string]interface{}) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "execute_cdp_cmd", map[string]interface{}{
 		"cmd":    cmd,
 		"params": params,
 	})
 }
 
-func (c *Client) UploadFile(selector string, localFilePath string) (map[string]interface{}, error) {
+func (s *BrowserSession) UploadFile(selector string, localFilePath string) (map[string]interface{}, error) {
+	return s.UploadFileContext(context.Background(), selector, localFilePath)
+}
+
+func (s *BrowserSession) UploadFileContext(ctx context.Context, selector string, localFilePath string) (map[string]interface{}, error) {
 	if _, err := os.Stat(localFilePath); os.IsNotExist(err) {
 		return map[string]interface{}{"status": "error", "error": fmt.Sprintf("Local file not found: %s", localFilePath)}, nil
 	}
@@ -90,7 +110,7 @@ func (c *Client) UploadFile(selector string, localFilePath string) (map[string]i
 	encodedData := base64.StdEncoding.EncodeToString(data)
 	filename := filepath.Base(localFilePath)
 
-	return c.Send("upload_file", map[string]interface{}{
+	return s.SendContext(ctx, "upload_file", map[string]interface{}{
 		"selector":  selector,
 		"file_name": filename,
 		"file_data": encodedData,
@@ -99,81 +119,149 @@ func (c *Client) UploadFile(selector string, localFilePath string) (map[string]i
 
 // --- Navigation ---
 
-func (c *Client) OpenURL(url string) (map[string]interface{}, error) {
-	return c.Send("open_url", map[string]interface{}{"url": url})
+func (s *BrowserSession) OpenURL(url string) (map[string]interface{}, error) {
+	return s.OpenURLContext(context.Background(), url)
 }
 
-func (c *Client) Reload(ignoreCache bool, script string) (map[string]interface{}, error) {
-	return c.Send("reload", map[string]interface{}{
+func (s *BrowserSession) OpenURLContext(ctx context.Context, url string) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "open_url", map[string]interface{}{"url": url})
+}
+
+func (s *BrowserSession) Reload(ignoreCache bool, script string) (map[string]interface{}, error) {
+	return s.ReloadContext(context.Background(), ignoreCache, script)
+}
+
+func (s *BrowserSession) ReloadContext(ctx context.Context, ignoreCache bool, script string) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "reload", map[string]interface{}{
 		"ignore_cache":               ignoreCache,
 		"script_to_evaluate_on_load": script,
 	})
 }
 
-func (c *Client) Refresh() (map[string]interface{}, error) {
-	return c.Send("refresh", nil)
+func (s *BrowserSession) Refresh() (map[string]interface{}, error) {
+	return s.RefreshContext(context.Background())
+}
+
+func (s *BrowserSession) RefreshContext(ctx context.Context) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "refresh", nil)
+}
+
+func (s *BrowserSession) GoBack() (map[string]interface{}, error) {
+	return s.GoBackContext(context.Background())
 }
 
-func (c *Client) GoBack() (map[string]interface{}, error) {
-	return c.Send("go_back", nil)
+func (s *BrowserSession) GoBackContext(ctx context.Context) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "go_back", nil)
 }
 
-func (c *Client) GoForward() (map[string]interface{}, error) {
-	return c.Send("go_forward", nil)
+func (s *BrowserSession) GoForward() (map[string]interface{}, error) {
+	return s.GoForwardContext(context.Background())
 }
 
-func (c *Client) InternalizeLinks() (map[string]interface{}, error) {
-	return c.Send("internalize_links", nil)
+func (s *BrowserSession) GoForwardContext(ctx context.Context) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "go_forward", nil)
 }
 
-func (c *Client) GetNavigationHistory() (map[string]interface{}, error) {
-	return c.Send("get_navigation_history", nil)
+func (s *BrowserSession) InternalizeLinks() (map[string]interface{}, error) {
+	return s.InternalizeLinksContext(context.Background())
+}
+
+func (s *BrowserSession) InternalizeLinksContext(ctx context.Context) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "internalize_links", nil)
+}
+
+func (s *BrowserSession) GetNavigationHistory() (NavigationHistoryResult, error) {
+	return s.GetNavigationHistoryContext(context.Background())
+}
+
+func (s *BrowserSession) GetNavigationHistoryContext(ctx context.Context) (NavigationHistoryResult, error) {
+	return SendTyped[NavigationHistoryResult](ctx, s, "get_navigation_history", nil)
 }
 
 // --- Interaction (Clicks & Typing) ---
 
-func (c *Client) Click(selector string, timeout int) (map[string]interface{}, error) {
+func (s *BrowserSession) Click(selector string, timeout int) (map[string]interface{}, error) {
+	return s.ClickContext(context.Background(), selector, timeout)
+}
+
+func (s *BrowserSession) ClickContext(ctx context.Context, selector string, timeout int) (map[string]interface{}, error) {
 	args := map[string]interface{}{"selector": selector}
 	if timeout > 0 {
 		args["timeout"] = timeout
 	}
-	return c.Send("click", args)
+	return s.SendContext(ctx, "click", args)
+}
+
+func (s *BrowserSession) ClickIfVisible(selector string) (map[string]interface{}, error) {
+	return s.ClickIfVisibleContext(context.Background(), selector)
+}
+
+func (s *BrowserSession) ClickIfVisibleContext(ctx context.Context, selector string) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "click_if_visible", map[string]interface{}{"selector": selector})
+}
+
+func (s *BrowserSession) ClickVisibleElements(selector string, limit int) (map[string]interface{}, error) {
+	return s.ClickVisibleElementsContext(context.Background(), selector, limit)
+}
+
+func (s *BrowserSession) ClickVisibleElementsContext(ctx context.Context, selector string, limit int) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "click_visible_elements", map[string]interface{}{"selector": selector, "limit": limit})
+}
+
+func (s *BrowserSession) ClickNthElement(selector string, number int) (map[string]interface{}, error) {
+	return s.ClickNthElementContext(context.Background(), selector, number)
+}
+
+func (s *BrowserSession) ClickNthElementContext(ctx context.Context, selector string, number int) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "click_nth_element", map[string]interface{}{"selector": selector, "number": number})
+}
+
+func (s *BrowserSession) ClickNthVisibleElement(selector string, number int) (map[string]interface{}, error) {
+	return s.ClickNthVisibleElementContext(context.Background(), selector, number)
+}
+
+func (s *BrowserSession) ClickNthVisibleElementContext(ctx context.Context, selector string, number int) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "click_nth_visible_element", map[string]interface{}{"selector": selector, "number": number})
 }
 
-func (c *Client) ClickIfVisible(selector string) (map[string]interface{}, error) {
-	return c.Send("click_if_visible", map[string]interface{}{"selector": selector})
+func (s *BrowserSession) ClickLink(text string) (map[string]interface{}, error) {
+	return s.ClickLinkContext(context.Background(), text)
 }
 
-func (c *Client) ClickVisibleElements(selector string, limit int) (map[string]interface{}, error) {
-	return c.Send("click_visible_elements", map[string]interface{}{"selector": selector, "limit": limit})
+func (s *BrowserSession) ClickLinkContext(ctx context.Context, text string) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "click_link", map[string]interface{}{"text": text})
 }
 
-func (c *Client) ClickNthElement(selector string, number int) (map[string]interface{}, error) {
-	return c.Send("click_nth_element", map[string]interface{}{"selector": selector, "number": number})
+func (s *BrowserSession) ClickActiveElement() (map[string]interface{}, error) {
+	return s.ClickActiveElementContext(context.Background())
 }
 
-func (c *Client) ClickNthVisibleElement(selector string, number int) (map[string]interface{}, error) {
-	return c.Send("click_nth_visible_element", map[string]interface{}{"selector": selector, "number": number})
+func (s *BrowserSession) ClickActiveElementContext(ctx context.Context) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "click_active_element", nil)
 }
 
-func (c *Client) ClickLink(text string) (map[string]interface{}, error) {
-	return c.Send("click_link", map[string]interface{}{"text": text})
+func (s *BrowserSession) MouseClick(selector string) (map[string]interface{}, error) {
+	return s.MouseClickContext(context.Background(), selector)
 }
 
-func (c *Client) ClickActiveElement() (map[string]interface{}, error) {
-	return c.Send("click_active_element", nil)
+func (s *BrowserSession) MouseClickContext(ctx context.Context, selector string) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "mouse_click", map[string]interface{}{"selector": selector})
 }
 
-func (c *Client) MouseClick(selector string) (map[string]interface{}, error) {
-	return c.Send("mouse_click", map[string]interface{}{"selector": selector})
+func (s *BrowserSession) NestedClick(parentSelector, selector string) (map[string]interface{}, error) {
+	return s.NestedClickContext(context.Background(), parentSelector, selector)
 }
 
-func (c *Client) NestedClick(parentSelector, selector string) (map[string]interface{}, error) {
-	return c.Send("nested_click", map[string]interface{}{"parent_selector": parentSelector, "selector": selector})
+func (s *BrowserSession) NestedClickContext(ctx context.Context, parentSelector, selector string) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "nested_click", map[string]interface{}{"parent_selector": parentSelector, "selector": selector})
 }
 
-func (c *Client) ClickWithOffset(selector string, x, y int, center bool) (map[string]interface{}, error) {
-	return c.Send("click_with_offset", map[string]interface{}{
+func (s *BrowserSession) ClickWithOffset(selector string, x, y int, center bool) (map[string]interface{}, error) {
+	return s.ClickWithOffsetContext(context.Background(), selector, x, y, center)
+}
+
+func (s *BrowserSession) ClickWithOffsetContext(ctx context.Context, selector string, x, y int, center bool) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "click_with_offset", map[string]interface{}{
 		"selector": selector,
 		"x":        x,
 		"y":        y,
@@ -181,231 +269,423 @@ func (c *Client) ClickWithOffset(selector string, x, y int, center bool) (map[st
 	})
 }
 
-func (c *Client) Type(selector, text string, timeout int) (map[string]interface{}, error) {
+func (s *BrowserSession) Type(selector, text string, timeout int) (map[string]interface{}, error) {
+	return s.TypeContext(context.Background(), selector, text, timeout)
+}
+
+func (s *BrowserSession) TypeContext(ctx context.Context, selector, text string, timeout int) (map[string]interface{}, error) {
 	args := map[string]interface{}{"selector": selector, "text": text}
 	if timeout > 0 {
 		args["timeout"] = timeout
 	}
-	return c.Send("type", args)
+	return s.SendContext(ctx, "type", args)
+}
+
+func (s *BrowserSession) PressKeys(selector, text string) (map[string]interface{}, error) {
+	return s.PressKeysContext(context.Background(), selector, text)
+}
+
+func (s *BrowserSession) PressKeysContext(ctx context.Context, selector, text string) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "press_keys", map[string]interface{}{"selector": selector, "text": text})
+}
+
+func (s *BrowserSession) SendKeys(selector, text string) (map[string]interface{}, error) {
+	return s.SendKeysContext(context.Background(), selector, text)
+}
+
+func (s *BrowserSession) SendKeysContext(ctx context.Context, selector, text string) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "send_keys", map[string]interface{}{"selector": selector, "text": text})
+}
+
+func (s *BrowserSession) SetValue(selector, text string) (map[string]interface{}, error) {
+	return s.SetValueContext(context.Background(), selector, text)
+}
+
+func (s *BrowserSession) SetValueContext(ctx context.Context, selector, text string) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "set_value", map[string]interface{}{"selector": selector, "text": text})
+}
+
+func (s *BrowserSession) Clear(selector string) (map[string]interface{}, error) {
+	return s.ClearContext(context.Background(), selector)
 }
 
-func (c *Client) PressKeys(selector, text string) (map[string]interface{}, error) {
-	return c.Send("press_keys", map[string]interface{}{"selector": selector, "text": text})
+func (s *BrowserSession) ClearContext(ctx context.Context, selector string) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "clear", map[string]interface{}{"selector": selector})
 }
 
-func (c *Client) SendKeys(selector, text string) (map[string]interface{}, error) {
-	return c.Send("send_keys", map[string]interface{}{"selector": selector, "text": text})
+func (s *BrowserSession) ClearInput(selector string) (map[string]interface{}, error) {
+	return s.ClearInputContext(context.Background(), selector)
 }
 
-func (c *Client) SetValue(selector, text string) (map[string]interface{}, error) {
-	return c.Send("set_value", map[string]interface{}{"selector": selector, "text": text})
+func (s *BrowserSession) ClearInputContext(ctx context.Context, selector string) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "clear_input", map[string]interface{}{"selector": selector})
 }
 
-func (c *Client) Clear(selector string) (map[string]interface{}, error) {
-	return c.Send("clear", map[string]interface{}{"selector": selector})
+func (s *BrowserSession) Submit(selector string) (map[string]interface{}, error) {
+	return s.SubmitContext(context.Background(), selector)
 }
 
-func (c *Client) ClearInput(selector string) (map[string]interface{}, error) {
-	return c.Send("clear_input", map[string]interface{}{"selector": selector})
+func (s *BrowserSession) SubmitContext(ctx context.Context, selector string) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "submit", map[string]interface{}{"selector": selector})
 }
 
-func (c *Client) Submit(selector string) (map[string]interface{}, error) {
-	return c.Send("submit", map[string]interface{}{"selector": selector})
+func (s *BrowserSession) Focus(selector string) (map[string]interface{}, error) {
+	return s.FocusContext(context.Background(), selector)
 }
 
-func (c *Client) Focus(selector string) (map[string]interface{}, error) {
-	return c.Send("focus", map[string]interface{}{"selector": selector})
+func (s *BrowserSession) FocusContext(ctx context.Context, selector string) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "focus", map[string]interface{}{"selector": selector})
 }
 
 // --- GUI (Human-like) ---
 
-func (c *Client) GuiClickElement(selector string, timeframe float64) (map[string]interface{}, error) {
+func (s *BrowserSession) GuiClickElement(selector string, timeframe float64) (map[string]interface{}, error) {
+	return s.GuiClickElementContext(context.Background(), selector, timeframe)
+}
+
+func (s *BrowserSession) GuiClickElementContext(ctx context.Context, selector string, timeframe float64) (map[string]interface{}, error) {
 	if timeframe == 0 {
 		timeframe = 0.25
 	}
-	return c.Send("gui_click_element", map[string]interface{}{"selector": selector, "timeframe": timeframe})
+	return s.SendContext(ctx, "gui_click_element", map[string]interface{}{"selector": selector, "timeframe": timeframe})
+}
+
+func (s *BrowserSession) GuiClickXY(x, y int, timeframe float64) (map[string]interface{}, error) {
+	return s.GuiClickXYContext(context.Background(), x, y, timeframe)
 }
 
-func (c *Client) GuiClickXY(x, y int, timeframe float64) (map[string]interface{}, error) {
+func (s *BrowserSession) GuiClickXYContext(ctx context.Context, x, y int, timeframe float64) (map[string]interface{}, error) {
 	if timeframe == 0 {
 		timeframe = 0.25
 	}
-	return c.Send("gui_click_x_y", map[string]interface{}{"x": x, "y": y, "timeframe": timeframe})
+	return s.SendContext(ctx, "gui_click_x_y", map[string]interface{}{"x": x, "y": y, "timeframe": timeframe})
+}
+
+func (s *BrowserSession) GuiClickCaptcha() (map[string]interface{}, error) {
+	return s.GuiClickCaptchaContext(context.Background())
+}
+
+func (s *BrowserSession) GuiClickCaptchaContext(ctx context.Context) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "gui_click_captcha", nil)
 }
 
-func (c *Client) GuiClickCaptcha() (map[string]interface{}, error) {
-	return c.Send("gui_click_captcha", nil)
+func (s *BrowserSession) SolveCaptcha() (map[string]interface{}, error) {
+	return s.SolveCaptchaContext(context.Background())
 }
 
-func (c *Client) SolveCaptcha() (map[string]interface{}, error) {
-	return c.Send("solve_captcha", nil)
+func (s *BrowserSession) SolveCaptchaContext(ctx context.Context) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "solve_captcha", nil)
 }
 
-func (c *Client) GuiDragAndDrop(dragSelector, dropSelector string, timeframe float64) (map[string]interface{}, error) {
+func (s *BrowserSession) GuiDragAndDrop(dragSelector, dropSelector string, timeframe float64) (map[string]interface{}, error) {
+	return s.GuiDragAndDropContext(context.Background(), dragSelector, dropSelector, timeframe)
+}
+
+func (s *BrowserSession) GuiDragAndDropContext(ctx context.Context, dragSelector, dropSelector string, timeframe float64) (map[string]interface{}, error) {
 	if timeframe == 0 {
 		timeframe = 0.35
 	}
-	return c.Send("gui_drag_and_drop", map[string]interface{}{
+	return s.SendContext(ctx, "gui_drag_and_drop", map[string]interface{}{
 		"drag_selector": dragSelector,
 		"drop_selector": dropSelector,
 		"timeframe":     timeframe,
 	})
 }
 
-func (c *Client) GuiHoverElement(selector string) (map[string]interface{}, error) {
-	return c.Send("gui_hover_element", map[string]interface{}{"selector": selector})
+func (s *BrowserSession) GuiHoverElement(selector string) (map[string]interface{}, error) {
+	return s.GuiHoverElementContext(context.Background(), selector)
+}
+
+func (s *BrowserSession) GuiHoverElementContext(ctx context.Context, selector string) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "gui_hover_element", map[string]interface{}{"selector": selector})
+}
+
+func (s *BrowserSession) GuiWrite(text string) (map[string]interface{}, error) {
+	return s.GuiWriteContext(context.Background(), text)
+}
+
+func (s *BrowserSession) GuiWriteContext(ctx context.Context, text string) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "gui_write", map[string]interface{}{"text": text})
 }
 
-func (c *Client) GuiWrite(text string) (map[string]interface{}, error) {
-	return c.Send("gui_write", map[string]interface{}{"text": text})
+func (s *BrowserSession) GuiPressKeys(keys []string) (map[string]interface{}, error) {
+	return s.GuiPressKeysContext(context.Background(), keys)
 }
 
-func (c *Client) GuiPressKeys(keys []string) (map[string]interface{}, error) {
-	return c.Send("gui_press_keys", map[string]interface{}{"keys": keys})
+func (s *BrowserSession) GuiPressKeysContext(ctx context.Context, keys []string) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "gui_press_keys", map[string]interface{}{"keys": keys})
 }
 
 // --- Select / Options ---
 
-func (c *Client) SelectOptionByText(selector, text string) (map[string]interface{}, error) {
-	return c.Send("select_option_by_text", map[string]interface{}{"selector": selector, "text": text})
+func (s *BrowserSession) SelectOptionByText(selector, text string) (map[string]interface{}, error) {
+	return s.SelectOptionByTextContext(context.Background(), selector, text)
+}
+
+func (s *BrowserSession) SelectOptionByTextContext(ctx context.Context, selector, text string) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "select_option_by_text", map[string]interface{}{"selector": selector, "text": text})
+}
+
+func (s *BrowserSession) SelectOptionByValue(selector, value string) (map[string]interface{}, error) {
+	return s.SelectOptionByValueContext(context.Background(), selector, value)
+}
+
+func (s *BrowserSession) SelectOptionByValueContext(ctx context.Context, selector, value string) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "select_option_by_value", map[string]interface{}{"selector": selector, "value": value})
 }
 
-func (c *Client) SelectOptionByValue(selector, value string) (map[string]interface{}, error) {
-	return c.Send("select_option_by_value", map[string]interface{}{"selector": selector, "value": value})
+func (s *BrowserSession) SelectOptionByIndex(selector string, index int) (map[string]interface{}, error) {
+	return s.SelectOptionByIndexContext(context.Background(), selector, index)
 }
 
-func (c *Client) SelectOptionByIndex(selector string, index int) (map[string]interface{}, error) {
-	return c.Send("select_option_by_index", map[string]interface{}{"selector": selector, "index": index})
+func (s *BrowserSession) SelectOptionByIndexContext(ctx context.Context, selector string, index int) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "select_option_by_index", map[string]interface{}{"selector": selector, "index": index})
 }
 
 // --- Windows & Tabs ---
 
-func (c *Client) OpenNewTab(url string) (map[string]interface{}, error) {
-	return c.Send("open_new_tab", map[string]interface{}{"url": url})
+func (s *BrowserSession) OpenNewTab(url string) (map[string]interface{}, error) {
+	return s.OpenNewTabContext(context.Background(), url)
 }
 
-func (c *Client) OpenNewWindow(url string) (map[string]interface{}, error) {
-	return c.Send("open_new_window", map[string]interface{}{"url": url})
+func (s *BrowserSession) OpenNewTabContext(ctx context.Context, url string) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "open_new_tab", map[string]interface{}{"url": url})
 }
 
-func (c *Client) SwitchToTab(index int) (map[string]interface{}, error) {
-	return c.Send("switch_to_tab", map[string]interface{}{"index": index})
+func (s *BrowserSession) OpenNewWindow(url string) (map[string]interface{}, error) {
+	return s.OpenNewWindowContext(context.Background(), url)
 }
 
-func (c *Client) SwitchToWindow(index int) (map[string]interface{}, error) {
-	return c.Send("switch_to_window", map[string]interface{}{"index": index})
+func (s *BrowserSession) OpenNewWindowContext(ctx context.Context, url string) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "open_new_window", map[string]interface{}{"url": url})
 }
 
-func (c *Client) CloseActiveTab() (map[string]interface{}, error) {
-	return c.Send("close_active_tab", nil)
+func (s *BrowserSession) SwitchToTab(index int) (map[string]interface{}, error) {
+	return s.SwitchToTabContext(context.Background(), index)
 }
 
-func (c *Client) Maximize() (map[string]interface{}, error) {
-	return c.Send("maximize", nil)
+func (s *BrowserSession) SwitchToTabContext(ctx context.Context, index int) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "switch_to_tab", map[string]interface{}{"index": index})
 }
 
-func (c *Client) Minimize() (map[string]interface{}, error) {
-	return c.Send("minimize", nil)
+func (s *BrowserSession) SwitchToWindow(index int) (map[string]interface{}, error) {
+	return s.SwitchToWindowContext(context.Background(), index)
 }
 
-func (c *Client) Medimize() (map[string]interface{}, error) {
-	return c.Send("medimize", nil)
+func (s *BrowserSession) SwitchToWindowContext(ctx context.Context, index int) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "switch_to_window", map[string]interface{}{"index": index})
 }
 
-func (c *Client) TileWindows() (map[string]interface{}, error) {
-	return c.Send("tile_windows", nil)
+func (s *BrowserSession) CloseActiveTab() (map[string]interface{}, error) {
+	return s.CloseActiveTabContext(context.Background())
+}
+
+func (s *BrowserSession) CloseActiveTabContext(ctx context.Context) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "close_active_tab", nil)
+}
+
+func (s *BrowserSession) Maximize() (map[string]interface{}, error) {
+	return s.MaximizeContext(context.Background())
+}
+
+func (s *BrowserSession) MaximizeContext(ctx context.Context) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "maximize", nil)
+}
+
+func (s *BrowserSession) Minimize() (map[string]interface{}, error) {
+	return s.MinimizeContext(context.Background())
+}
+
+func (s *BrowserSession) MinimizeContext(ctx context.Context) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "minimize", nil)
+}
+
+func (s *BrowserSession) Medimize() (map[string]interface{}, error) {
+	return s.MedimizeContext(context.Background())
+}
+
+func (s *BrowserSession) MedimizeContext(ctx context.Context) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "medimize", nil)
+}
+
+func (s *BrowserSession) TileWindows() (map[string]interface{}, error) {
+	return s.TileWindowsContext(context.Background())
+}
+
+func (s *BrowserSession) TileWindowsContext(ctx context.Context) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "tile_windows", nil)
 }
 
 // --- Getters ---
 
-func (c *Client) GetText(selector string) (map[string]interface{}, error) {
+func (s *BrowserSession) GetText(selector string) (StringResult, error) {
+	return s.GetTextContext(context.Background(), selector)
+}
+
+func (s *BrowserSession) GetTextContext(ctx context.Context, selector string) (StringResult, error) {
 	if selector == "" {
 		selector = "body"
 	}
-	return c.Send("get_text", map[string]interface{}{"selector": selector})
+	return SendTyped[StringResult](ctx, s, "get_text", map[string]interface{}{"selector": selector})
+}
+
+func (s *BrowserSession) GetTitle() (StringResult, error) {
+	return s.GetTitleContext(context.Background())
+}
+
+func (s *BrowserSession) GetTitleContext(ctx context.Context) (StringResult, error) {
+	return SendTyped[StringResult](ctx, s, "get_title", nil)
+}
+
+func (s *BrowserSession) GetCurrentURL() (StringResult, error) {
+	return s.GetCurrentURLContext(context.Background())
+}
+
+func (s *BrowserSession) GetCurrentURLContext(ctx context.Context) (StringResult, error) {
+	return SendTyped[StringResult](ctx, s, "get_current_url", nil)
 }
 
-func (c *Client) GetTitle() (map[string]interface{}, error) {
-	return c.Send("get_title", nil)
+func (s *BrowserSession) GetPageSource() (StringResult, error) {
+	return s.GetPageSourceContext(context.Background())
 }
 
-func (c *Client) GetCurrentURL() (map[string]interface{}, error) {
-	return c.Send("get_current_url", nil)
+func (s *BrowserSession) GetPageSourceContext(ctx context.Context) (StringResult, error) {
+	return SendTyped[StringResult](ctx, s, "get_page_source", nil)
 }
 
-func (c *Client) GetPageSource() (map[string]interface{}, error) {
-	return c.Send("get_page_source", nil)
+func (s *BrowserSession) GetHTML(selector string) (StringResult, error) {
+	return s.GetHTMLContext(context.Background(), selector)
 }
 
-func (c *Client) GetHTML(selector string) (map[string]interface{}, error) {
-	return c.Send("get_html", map[string]interface{}{"selector": selector})
+func (s *BrowserSession) GetHTMLContext(ctx context.Context, selector string) (StringResult, error) {
+	return SendTyped[StringResult](ctx, s, "get_html", map[string]interface{}{"selector": selector})
 }
 
-func (c *Client) GetAttribute(selector, attribute string) (map[string]interface{}, error) {
-	return c.Send("get_attribute", map[string]interface{}{"selector": selector, "attribute": attribute})
+func (s *BrowserSession) GetAttribute(selector, attribute string) (StringResult, error) {
+	return s.GetAttributeContext(context.Background(), selector, attribute)
 }
 
-func (c *Client) GetElementAttributes(selector string) (map[string]interface{}, error) {
-	return c.Send("get_element_attributes", map[string]interface{}{"selector": selector})
+func (s *BrowserSession) GetAttributeContext(ctx context.Context, selector, attribute string) (StringResult, error) {
+	return SendTyped[StringResult](ctx, s, "get_attribute", map[string]interface{}{"selector": selector, "attribute": attribute})
 }
 
-func (c *Client) GetUserAgent() (map[string]interface{}, error) {
-	return c.Send("get_user_agent", nil)
+func (s *BrowserSession) GetElementAttributes(selector string) (map[string]interface{}, error) {
+	return s.GetElementAttributesContext(context.Background(), selector)
 }
 
-func (c *Client) GetCookieString() (map[string]interface{}, error) {
-	return c.Send("get_cookie_string", nil)
+func (s *BrowserSession) GetElementAttributesContext(ctx context.Context, selector string) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "get_element_attributes", map[string]interface{}{"selector": selector})
 }
 
-func (c *Client) GetElementRect(selector string) (map[string]interface{}, error) {
-	return c.Send("get_element_rect", map[string]interface{}{"selector": selector})
+func (s *BrowserSession) GetUserAgent() (StringResult, error) {
+	return s.GetUserAgentContext(context.Background())
 }
 
-func (c *Client) GetWindowRect() (map[string]interface{}, error) {
-	return c.Send("get_window_rect", nil)
+func (s *BrowserSession) GetUserAgentContext(ctx context.Context) (StringResult, error) {
+	return SendTyped[StringResult](ctx, s, "get_user_agent", nil)
 }
 
-func (c *Client) GetScreenRect() (map[string]interface{}, error) {
-	return c.Send("get_screen_rect", nil)
+func (s *BrowserSession) GetCookieString() (StringResult, error) {
+	return s.GetCookieStringContext(context.Background())
 }
 
-func (c *Client) IsElementVisible(selector string) (map[string]interface{}, error) {
-	return c.Send("is_element_visible", map[string]interface{}{"selector": selector})
+func (s *BrowserSession) GetCookieStringContext(ctx context.Context) (StringResult, error) {
+	return SendTyped[StringResult](ctx, s, "get_cookie_string", nil)
 }
 
-func (c *Client) IsTextVisible(text string) (map[string]interface{}, error) {
-	return c.Send("is_text_visible", map[string]interface{}{"text": text})
+func (s *BrowserSession) GetElementRect(selector string) (RectResult, error) {
+	return s.GetElementRectContext(context.Background(), selector)
 }
 
-func (c *Client) IsChecked(selector string) (map[string]interface{}, error) {
-	return c.Send("is_checked", map[string]interface{}{"selector": selector})
+func (s *BrowserSession) GetElementRectContext(ctx context.Context, selector string) (RectResult, error) {
+	return SendTyped[RectResult](ctx, s, "get_element_rect", map[string]interface{}{"selector": selector})
 }
 
-func (c *Client) IsSelected(selector string) (map[string]interface{}, error) {
-	return c.Send("is_selected", map[string]interface{}{"selector": selector})
+func (s *BrowserSession) GetWindowRect() (RectResult, error) {
+	return s.GetWindowRectContext(context.Background())
 }
 
-func (c *Client) IsOnline() (map[string]interface{}, error) {
-	return c.Send("is_online", nil)
+func (s *BrowserSession) GetWindowRectContext(ctx context.Context) (RectResult, error) {
+	return SendTyped[RectResult](ctx, s, "get_window_rect", nil)
 }
 
-func (c *Client) GetPerformanceMetrics() (map[string]interface{}, error) {
-	return c.Send("get_performance_metrics", nil)
+func (s *BrowserSession) GetScreenRect() (RectResult, error) {
+	return s.GetScreenRectContext(context.Background())
+}
+
+func (s *BrowserSession) GetScreenRectContext(ctx context.Context) (RectResult, error) {
+	return SendTyped[RectResult](ctx, s, "get_screen_rect", nil)
+}
+
+func (s *BrowserSession) IsElementVisible(selector string) (BoolResult, error) {
+	return s.IsElementVisibleContext(context.Background(), selector)
+}
+
+func (s *BrowserSession) IsElementVisibleContext(ctx context.Context, selector string) (BoolResult, error) {
+	return SendTyped[BoolResult](ctx, s, "is_element_visible", map[string]interface{}{"selector": selector})
+}
+
+func (s *BrowserSession) IsTextVisible(text string) (BoolResult, error) {
+	return s.IsTextVisibleContext(context.Background(), text)
+}
+
+func (s *BrowserSession) IsTextVisibleContext(ctx context.Context, text string) (BoolResult, error) {
+	return SendTyped[BoolResult](ctx, s, "is_text_visible", map[string]interface{}{"text": text})
+}
+
+func (s *BrowserSession) IsChecked(selector string) (BoolResult, error) {
+	return s.IsCheckedContext(context.Background(), selector)
+}
+
+func (s *BrowserSession) IsCheckedContext(ctx context.Context, selector string) (BoolResult, error) {
+	return SendTyped[BoolResult](ctx, s, "is_checked", map[string]interface{}{"selector": selector})
+}
+
+func (s *BrowserSession) IsSelected(selector string) (BoolResult, error) {
+	return s.IsSelectedContext(context.Background(), selector)
+}
+
+func (s *BrowserSession) IsSelectedContext(ctx context.Context, selector string) (BoolResult, error) {
+	return SendTyped[BoolResult](ctx, s, "is_selected", map[string]interface{}{"selector": selector})
+}
+
+func (s *BrowserSession) IsOnline() (BoolResult, error) {
+	return s.IsOnlineContext(context.Background())
+}
+
+func (s *BrowserSession) IsOnlineContext(ctx context.Context) (BoolResult, error) {
+	return SendTyped[BoolResult](ctx, s, "is_online", nil)
+}
+
+func (s *BrowserSession) GetPerformanceMetrics() (PerformanceMetricsResult, error) {
+	return s.GetPerformanceMetricsContext(context.Background())
+}
+
+func (s *BrowserSession) GetPerformanceMetricsContext(ctx context.Context) (PerformanceMetricsResult, error) {
+	return SendTyped[PerformanceMetricsResult](ctx, s, "get_performance_metrics", nil)
 }
 
 // --- Cookies & Storage ---
 
-func (c *Client) GetAllCookies() (map[string]interface{}, error) {
-	return c.Send("get_all_cookies", nil)
+func (s *BrowserSession) GetAllCookies() (CookiesResult, error) {
+	return s.GetAllCookiesContext(context.Background())
 }
 
-func (c *Client) SaveCookies(name string) (map[string]interface{}, error) {
+func (s *BrowserSession) GetAllCookiesContext(ctx context.Context) (CookiesResult, error) {
+	return SendTyped[CookiesResult](ctx, s, "get_all_cookies", nil)
+}
+
+func (s *BrowserSession) SaveCookies(name string) (map[string]interface{}, error) {
+	return s.SaveCookiesContext(context.Background(), name)
+}
+
+func (s *BrowserSession) SaveCookiesContext(ctx context.Context, name string) (map[string]interface{}, error) {
 	if name == "" {
 		name = "cookies.txt"
 	}
-	res, err := c.Send("save_cookies", nil)
+	res, err := s.SendContext(ctx, "save_cookies", nil)
 	if err != nil {
-		return nil, err
+		return res, err
 	}
 
 	if status, ok := res["status"].(string); ok && status == "ok" {
@@ -421,7 +701,11 @@ func (c *Client) SaveCookies(name string) (map[string]interface{}, error) {
 	return res, nil
 }
 
-func (c *Client) LoadCookies(name string, cookiesList interface{}) (map[string]interface{}, error) {
+func (s *BrowserSession) LoadCookies(name string, cookiesList interface{}) (map[string]interface{}, error) {
+	return s.LoadCookiesContext(context.Background(), name, cookiesList)
+}
+
+func (s *BrowserSession) LoadCookiesContext(ctx context.Context, name string, cookiesList interface{}) (map[string]interface{}, error) {
 	finalCookies := cookiesList
 
 	// If no list provided, load from file
@@ -441,130 +725,234 @@ func (c *Client) LoadCookies(name string, cookiesList interface{}) (map[string]i
 		finalCookies = loaded
 	}
 
-	return c.Send("load_cookies", map[string]interface{}{
+	return s.SendContext(ctx, "load_cookies", map[string]interface{}{
 		"name":    name,
 		"cookies": finalCookies,
 	})
 }
 
-func (c *Client) ClearCookies() (map[string]interface{}, error) {
-	return c.Send("clear_cookies", nil)
+func (s *BrowserSession) ClearCookies() (map[string]interface{}, error) {
+	return s.ClearCookiesContext(context.Background())
+}
+
+func (s *BrowserSession) ClearCookiesContext(ctx context.Context) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "clear_cookies", nil)
+}
+
+func (s *BrowserSession) GetLocalStorageItem(key string) (StringResult, error) {
+	return s.GetLocalStorageItemContext(context.Background(), key)
+}
+
+func (s *BrowserSession) GetLocalStorageItemContext(ctx context.Context, key string) (StringResult, error) {
+	return SendTyped[StringResult](ctx, s, "get_local_storage_item", map[string]interface{}{"key": key})
+}
+
+func (s *BrowserSession) SetLocalStorageItem(key, value string) (map[string]interface{}, error) {
+	return s.SetLocalStorageItemContext(context.Background(), key, value)
+}
+
+func (s *BrowserSession) SetLocalStorageItemContext(ctx context.Context, key, value string) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "set_local_storage_item", map[string]interface{}{"key": key, "value": value})
 }
 
-func (c *Client) GetLocalStorageItem(key string) (map[string]interface{}, error) {
-	return c.Send("get_local_storage_item", map[string]interface{}{"key": key})
+func (s *BrowserSession) GetSessionStorageItem(key string) (StringResult, error) {
+	return s.GetSessionStorageItemContext(context.Background(), key)
 }
 
-func (c *Client) SetLocalStorageItem(key, value string) (map[string]interface{}, error) {
-	return c.Send("set_local_storage_item", map[string]interface{}{"key": key, "value": value})
+func (s *BrowserSession) GetSessionStorageItemContext(ctx context.Context, key string) (StringResult, error) {
+	return SendTyped[StringResult](ctx, s, "get_session_storage_item", map[string]interface{}{"key": key})
 }
 
-func (c *Client) GetSessionStorageItem(key string) (map[string]interface{}, error) {
-	return c.Send("get_session_storage_item", map[string]interface{}{"key": key})
+func (s *BrowserSession) SetSessionStorageItem(key, value string) (map[string]interface{}, error) {
+	return s.SetSessionStorageItemContext(context.Background(), key, value)
 }
 
-func (c *Client) SetSessionStorageItem(key, value string) (map[string]interface{}, error) {
-	return c.Send("set_session_storage_item", map[string]interface{}{"key": key, "value": value})
+func (s *BrowserSession) SetSessionStorageItemContext(ctx context.Context, key, value string) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "set_session_storage_item", map[string]interface{}{"key": key, "value": value})
 }
 
-func (c *Client) ExportSession() (map[string]interface{}, error) {
-	return c.Send("get_storage_state", nil)
+func (s *BrowserSession) ExportSession() (map[string]interface{}, error) {
+	return s.ExportSessionContext(context.Background())
 }
 
-func (c *Client) ImportSession(stateDict map[string]interface{}) (map[string]interface{}, error) {
-	return c.Send("set_storage_state", map[string]interface{}{"state": stateDict})
+func (s *BrowserSession) ExportSessionContext(ctx context.Context) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "get_storage_state", nil)
+}
+
+func (s *BrowserSession) ImportSession(stateDict map[string]interface{}) (map[string]interface{}, error) {
+	return s.ImportSessionContext(context.Background(), stateDict)
+}
+
+func (s *BrowserSession) ImportSessionContext(ctx context.Context, stateDict map[string]interface{}) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "set_storage_state", map[string]interface{}{"state": stateDict})
 }
 
 // --- Visual & Security ---
 
-func (c *Client) Highlight(selector string) (map[string]interface{}, error) {
-	return c.Send("highlight", map[string]interface{}{"selector": selector})
+func (s *BrowserSession) Highlight(selector string) (map[string]interface{}, error) {
+	return s.HighlightContext(context.Background(), selector)
+}
+
+func (s *BrowserSession) HighlightContext(ctx context.Context, selector string) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "highlight", map[string]interface{}{"selector": selector})
+}
+
+func (s *BrowserSession) HighlightOverlay(selector string) (map[string]interface{}, error) {
+	return s.HighlightOverlayContext(context.Background(), selector)
+}
+
+func (s *BrowserSession) HighlightOverlayContext(ctx context.Context, selector string) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "highlight_overlay", map[string]interface{}{"selector": selector})
 }
 
-func (c *Client) HighlightOverlay(selector string) (map[string]interface{}, error) {
-	return c.Send("highlight_overlay", map[string]interface{}{"selector": selector})
+func (s *BrowserSession) RemoveElement(selector string) (map[string]interface{}, error) {
+	return s.RemoveElementContext(context.Background(), selector)
 }
 
-func (c *Client) RemoveElement(selector string) (map[string]interface{}, error) {
-	return c.Send("remove_element", map[string]interface{}{"selector": selector})
+func (s *BrowserSession) RemoveElementContext(ctx context.Context, selector string) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "remove_element", map[string]interface{}{"selector": selector})
 }
 
-func (c *Client) Flash(selector string, duration float64) (map[string]interface{}, error) {
+func (s *BrowserSession) Flash(selector string, duration float64) (map[string]interface{}, error) {
+	return s.FlashContext(context.Background(), selector, duration)
+}
+
+func (s *BrowserSession) FlashContext(ctx context.Context, selector string, duration float64) (map[string]interface{}, error) {
 	if duration == 0 {
 		duration = 1
 	}
-	return c.Send("flash", map[string]interface{}{"selector": selector, "duration": duration})
+	return s.SendContext(ctx, "flash", map[string]interface{}{"selector": selector, "duration": duration})
+}
+
+func (s *BrowserSession) GetMFACode(totpKey string) (StringResult, error) {
+	return s.GetMFACodeContext(context.Background(), totpKey)
 }
 
-func (c *Client) GetMFACode(totpKey string) (map[string]interface{}, error) {
-	return c.Send("get_mfa_code", map[string]interface{}{"totp_key": totpKey})
+func (s *BrowserSession) GetMFACodeContext(ctx context.Context, totpKey string) (StringResult, error) {
+	return SendTyped[StringResult](ctx, s, "get_mfa_code", map[string]interface{}{"totp_key": totpKey})
 }
 
-func (c *Client) EnterMFACode(selector, totpKey string) (map[string]interface{}, error) {
-	return c.Send("enter_mfa_code", map[string]interface{}{"selector": selector, "totp_key": totpKey})
+func (s *BrowserSession) EnterMFACode(selector, totpKey string) (map[string]interface{}, error) {
+	return s.EnterMFACodeContext(context.Background(), selector, totpKey)
 }
 
-func (c *Client) GrantPermissions(permissions []string) (map[string]interface{}, error) {
-	return c.Send("grant_permissions", map[string]interface{}{"permissions": permissions})
+func (s *BrowserSession) EnterMFACodeContext(ctx context.Context, selector, totpKey string) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "enter_mfa_code", map[string]interface{}{"selector": selector, "totp_key": totpKey})
 }
 
-func (c *Client) ExecuteScript(script string) (map[string]interface{}, error) {
-	return c.Send("execute_script", map[string]interface{}{"script": script})
+func (s *BrowserSession) GrantPermissions(permissions []string) (map[string]interface{}, error) {
+	return s.GrantPermissionsContext(context.Background(), permissions)
 }
 
-func (c *Client) Evaluate(expression string) (map[string]interface{}, error) {
-	return c.Send("evaluate", map[string]interface{}{"expression": expression})
+func (s *BrowserSession) GrantPermissionsContext(ctx context.Context, permissions []string) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "grant_permissions", map[string]interface{}{"permissions": permissions})
 }
 
-func (c *Client) BlockURLs(patterns []string) (map[string]interface{}, error) {
-	return c.Send("block_urls", map[string]interface{}{"patterns": patterns})
+func (s *BrowserSession) ExecuteScript(script string) (map[string]interface{}, error) {
+	return s.ExecuteScriptContext(context.Background(), script)
+}
+
+func (s *BrowserSession) ExecuteScriptContext(ctx context.Context, script string) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "execute_script", map[string]interface{}{"script": script})
+}
+
+func (s *BrowserSession) Evaluate(expression string) (map[string]interface{}, error) {
+	return s.EvaluateContext(context.Background(), expression)
+}
+
+func (s *BrowserSession) EvaluateContext(ctx context.Context, expression string) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "evaluate", map[string]interface{}{"expression": expression})
+}
+
+func (s *BrowserSession) BlockURLs(patterns []string) (map[string]interface{}, error) {
+	return s.BlockURLsContext(context.Background(), patterns)
+}
+
+func (s *BrowserSession) BlockURLsContext(ctx context.Context, patterns []string) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "block_urls", map[string]interface{}{"patterns": patterns})
 }
 
 // --- Scrolling & Waiting ---
 
-func (c *Client) ScrollIntoView(selector string) (map[string]interface{}, error) {
-	return c.Send("scroll_into_view", map[string]interface{}{"selector": selector})
+func (s *BrowserSession) ScrollIntoView(selector string) (map[string]interface{}, error) {
+	return s.ScrollIntoViewContext(context.Background(), selector)
+}
+
+func (s *BrowserSession) ScrollIntoViewContext(ctx context.Context, selector string) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "scroll_into_view", map[string]interface{}{"selector": selector})
 }
 
-func (c *Client) ScrollToBottom() (map[string]interface{}, error) {
-	return c.Send("scroll_to_bottom", nil)
+func (s *BrowserSession) ScrollToBottom() (map[string]interface{}, error) {
+	return s.ScrollToBottomContext(context.Background())
 }
 
-func (c *Client) ScrollToTop() (map[string]interface{}, error) {
-	return c.Send("scroll_to_top", nil)
+func (s *BrowserSession) ScrollToBottomContext(ctx context.Context) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "scroll_to_bottom", nil)
 }
 
-func (c *Client) ScrollDown(amount int) (map[string]interface{}, error) {
+func (s *BrowserSession) ScrollToTop() (map[string]interface{}, error) {
+	return s.ScrollToTopContext(context.Background())
+}
+
+func (s *BrowserSession) ScrollToTopContext(ctx context.Context) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "scroll_to_top", nil)
+}
+
+func (s *BrowserSession) ScrollDown(amount int) (map[string]interface{}, error) {
+	return s.ScrollDownContext(context.Background(), amount)
+}
+
+func (s *BrowserSession) ScrollDownContext(ctx context.Context, amount int) (map[string]interface{}, error) {
 	if amount == 0 {
 		amount = 25
 	}
-	return c.Send("scroll_down", map[string]interface{}{"amount": amount})
+	return s.SendContext(ctx, "scroll_down", map[string]interface{}{"amount": amount})
 }
 
-func (c *Client) ScrollUp(amount int) (map[string]interface{}, error) {
+func (s *BrowserSession) ScrollUp(amount int) (map[string]interface{}, error) {
+	return s.ScrollUpContext(context.Background(), amount)
+}
+
+func (s *BrowserSession) ScrollUpContext(ctx context.Context, amount int) (map[string]interface{}, error) {
 	if amount == 0 {
 		amount = 25
 	}
-	return c.Send("scroll_up", map[string]interface{}{"amount": amount})
+	return s.SendContext(ctx, "scroll_up", map[string]interface{}{"amount": amount})
+}
+
+func (s *BrowserSession) ScrollToY(y int) (map[string]interface{}, error) {
+	return s.ScrollToYContext(context.Background(), y)
 }
 
-func (c *Client) ScrollToY(y int) (map[string]interface{}, error) {
-	return c.Send("scroll_to_y", map[string]interface{}{"y": y})
+func (s *BrowserSession) ScrollToYContext(ctx context.Context, y int) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "scroll_to_y", map[string]interface{}{"y": y})
 }
 
-func (c *Client) Sleep(seconds float64) (map[string]interface{}, error) {
-	return c.Send("sleep", map[string]interface{}{"seconds": seconds})
+func (s *BrowserSession) Sleep(seconds float64) (map[string]interface{}, error) {
+	return s.SleepContext(context.Background(), seconds)
 }
 
-func (c *Client) WaitForElement(selector string, timeout int) (map[string]interface{}, error) {
+func (s *BrowserSession) SleepContext(ctx context.Context, seconds float64) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "sleep", map[string]interface{}{"seconds": seconds})
+}
+
+func (s *BrowserSession) WaitForElement(selector string, timeout int) (map[string]interface{}, error) {
+	return s.WaitForElementContext(context.Background(), selector, timeout)
+}
+
+func (s *BrowserSession) WaitForElementContext(ctx context.Context, selector string, timeout int) (map[string]interface{}, error) {
 	args := map[string]interface{}{"selector": selector}
 	if timeout > 0 {
 		args["timeout"] = timeout
 	}
-	return c.Send("wait_for_element", args)
+	return s.SendContext(ctx, "wait_for_element", args)
 }
 
-func (c *Client) WaitForText(text, selector string, timeout int) (map[string]interface{}, error) {
+func (s *BrowserSession) WaitForText(text, selector string, timeout int) (map[string]interface{}, error) {
+	return s.WaitForTextContext(context.Background(), text, selector, timeout)
+}
+
+func (s *BrowserSession) WaitForTextContext(ctx context.Context, text, selector string, timeout int) (map[string]interface{}, error) {
 	if selector == "" {
 		selector = "html"
 	}
@@ -572,49 +960,64 @@ func (c *Client) WaitForText(text, selector string, timeout int) (map[string]int
 	if timeout > 0 {
 		args["timeout"] = timeout
 	}
-	return c.Send("wait_for_text", args)
+	return s.SendContext(ctx, "wait_for_text", args)
 }
 
-func (c *Client) WaitForElementPresent(selector string, timeout int) (map[string]interface{}, error) {
+func (s *BrowserSession) WaitForElementPresent(selector string, timeout int) (map[string]interface{}, error) {
+	return s.WaitForElementPresentContext(context.Background(), selector, timeout)
+}
+
+func (s *BrowserSession) WaitForElementPresentContext(ctx context.Context, selector string, timeout int) (map[string]interface{}, error) {
 	args := map[string]interface{}{"selector": selector}
 	if timeout > 0 {
 		args["timeout"] = timeout
 	}
-	return c.Send("wait_for_element_present", args)
+	return s.SendContext(ctx, "wait_for_element_present", args)
+}
+
+func (s *BrowserSession) WaitForElementAbsent(selector string, timeout int) (map[string]interface{}, error) {
+	return s.WaitForElementAbsentContext(context.Background(), selector, timeout)
 }
 
-func (c *Client) WaitForElementAbsent(selector string, timeout int) (map[string]interface{}, error) {
+func (s *BrowserSession) WaitForElementAbsentContext(ctx context.Context, selector string, timeout int) (map[string]interface{}, error) {
 	args := map[string]interface{}{"selector": selector}
 	if timeout > 0 {
 		args["timeout"] = timeout
 	}
-	return c.Send("wait_for_element_absent", args)
+	return s.SendContext(ctx, "wait_for_element_absent", args)
 }
 
-func (c *Client) WaitForNetworkIdle() (map[string]interface{}, error) {
-	return c.Send("wait_for_network_idle", nil)
+func (s *BrowserSession) WaitForNetworkIdle() (map[string]interface{}, error) {
+	return s.WaitForNetworkIdleContext(context.Background())
+}
+
+func (s *BrowserSession) WaitForNetworkIdleContext(ctx context.Context) (map[string]interface{}, error) {
+	return s.SendContext(ctx, "wait_for_network_idle", nil)
 }
 
 // --- Assertions ---
 
-func (c *Client) handleAssertion(action string, args map[string]interface{}) (map[string]interface{}, error) {
+func (s *BrowserSession) handleAssertion(ctx context.Context, action string, args map[string]interface{}) (map[string]interface{}, error) {
 	if _, ok := args["screenshot"]; !ok {
 		args["screenshot"] = true
 	}
 
-	res, err := c.Send(action, args)
+	// An *ActionError means the worker could not evaluate the check at all:
+	// that is not an assertion failure.
+	res, err := s.SendContext(ctx, action, args)
 	if err != nil {
-		return nil, err
+		return res, err
 	}
 
 	if status, ok := res["status"].(string); ok && status == "fail" {
 		// Handle automatic screenshot on failure
+		var screenshotPath string
 		if b64, ok := res["screenshot_base64"].(string); ok {
 			_ = os.MkdirAll(AssertionFolder, 0755)
 
 			selector := "unknown"
-			if s, ok := args["selector"].(string); ok {
-				selector = cleanSelector(s)
+			if sel, ok := args["selector"].(string); ok {
+				selector = cleanSelector(sel)
 			}
 
 			timestamp := time.Now().Format("150405")
@@ -622,8 +1025,12 @@ func (c *Client) handleAssertion(action string, args map[string]interface{}) (ma
 			path := filepath.Join(AssertionFolder, filename)
 
 			if data, err := base64.StdEncoding.DecodeString(b64); err == nil {
-				_ = os.WriteFile(path, data, 0644)
-				fmt.Printf("[Assertion Fail] Screenshot saved: %s\n", path)
+				if err := os.WriteFile(path, data, 0644); err == nil {
+					screenshotPath = path
+					s.client.logger.LogAttrs(ctx, slog.LevelInfo, "assertion failure screenshot saved",
+						slog.String("action", action),
+						slog.String("path", path))
+				}
 			}
 		}
 
@@ -632,77 +1039,122 @@ func (c *Client) handleAssertion(action string, args map[string]interface{}) (ma
 			errMsg = e
 		}
 		// In Go, we return an error rather than raising an exception
-		return res, NewBrowserError("Assertion Failed: %s", errMsg)
+		return res, &AssertionError{
+			Action:         action,
+			Message:        errMsg,
+			ScreenshotPath: screenshotPath,
+			Response:       res,
+		}
 	}
 
 	return res, nil
 }
 
-func (c *Client) AssertText(text, selector string, screenshot bool) (map[string]interface{}, error) {
+func (s *BrowserSession) AssertText(text, selector string, screenshot bool) (map[string]interface{}, error) {
+	return s.AssertTextContext(context.Background(), text, selector, screenshot)
+}
+
+func (s *BrowserSession) AssertTextContext(ctx context.Context, text, selector string, screenshot bool) (map[string]interface{}, error) {
 	if selector == "" {
 		selector = "html"
 	}
-	return c.handleAssertion("assert_text", map[string]interface{}{
+	return s.handleAssertion(ctx, "assert_text", map[string]interface{}{
 		"text": text, "selector": selector, "screenshot": screenshot,
 	})
 }
 
-func (c *Client) AssertExactText(text, selector string, screenshot bool) (map[string]interface{}, error) {
+func (s *BrowserSession) AssertExactText(text, selector string, screenshot bool) (map[string]interface{}, error) {
+	return s.AssertExactTextContext(context.Background(), text, selector, screenshot)
+}
+
+func (s *BrowserSession) AssertExactTextContext(ctx context.Context, text, selector string, screenshot bool) (map[string]interface{}, error) {
 	if selector == "" {
 		selector = "html"
 	}
-	return c.handleAssertion("assert_exact_text", map[string]interface{}{
+	return s.handleAssertion(ctx, "assert_exact_text", map[string]interface{}{
 		"text": text, "selector": selector, "screenshot": screenshot,
 	})
 }
 
-func (c *Client) AssertElement(selector string, screenshot bool) (map[string]interface{}, error) {
-	return c.handleAssertion("assert_element", map[string]interface{}{
+func (s *BrowserSession) AssertElement(selector string, screenshot bool) (map[string]interface{}, error) {
+	return s.AssertElementContext(context.Background(), selector, screenshot)
+}
+
+func (s *BrowserSession) AssertElementContext(ctx context.Context, selector string, screenshot bool) (map[string]interface{}, error) {
+	return s.handleAssertion(ctx, "assert_element", map[string]interface{}{
 		"selector": selector, "screenshot": screenshot,
 	})
 }
 
-func (c *Client) AssertElementPresent(selector string, screenshot bool) (map[string]interface{}, error) {
-	return c.handleAssertion("assert_element_present", map[string]interface{}{
+func (s *BrowserSession) AssertElementPresent(selector string, screenshot bool) (map[string]interface{}, error) {
+	return s.AssertElementPresentContext(context.Background(), selector, screenshot)
+}
+
+func (s *BrowserSession) AssertElementPresentContext(ctx context.Context, selector string, screenshot bool) (map[string]interface{}, error) {
+	return s.handleAssertion(ctx, "assert_element_present", map[string]interface{}{
 		"selector": selector, "screenshot": screenshot,
 	})
 }
 
-func (c *Client) AssertElementAbsent(selector string, screenshot bool) (map[string]interface{}, error) {
-	return c.handleAssertion("assert_element_absent", map[string]interface{}{
+func (s *BrowserSession) AssertElementAbsent(selector string, screenshot bool) (map[string]interface{}, error) {
+	return s.AssertElementAbsentContext(context.Background(), selector, screenshot)
+}
+
+func (s *BrowserSession) AssertElementAbsentContext(ctx context.Context, selector string, screenshot bool) (map[string]interface{}, error) {
+	return s.handleAssertion(ctx, "assert_element_absent", map[string]interface{}{
 		"selector": selector, "screenshot": screenshot,
 	})
 }
 
-func (c *Client) AssertElementNotVisible(selector string, screenshot bool) (map[string]interface{}, error) {
-	return c.handleAssertion("assert_element_not_visible", map[string]interface{}{
+func (s *BrowserSession) AssertElementNotVisible(selector string, screenshot bool) (map[string]interface{}, error) {
+	return s.AssertElementNotVisibleContext(context.Background(), selector, screenshot)
+}
+
+func (s *BrowserSession) AssertElementNotVisibleContext(ctx context.Context, selector string, screenshot bool) (map[string]interface{}, error) {
+	return s.handleAssertion(ctx, "assert_element_not_visible", map[string]interface{}{
 		"selector": selector, "screenshot": screenshot,
 	})
 }
 
-func (c *Client) AssertTextNotVisible(text, selector string, screenshot bool) (map[string]interface{}, error) {
+func (s *BrowserSession) AssertTextNotVisible(text, selector string, screenshot bool) (map[string]interface{}, error) {
+	return s.AssertTextNotVisibleContext(context.Background(), text, selector, screenshot)
+}
+
+func (s *BrowserSession) AssertTextNotVisibleContext(ctx context.Context, text, selector string, screenshot bool) (map[string]interface{}, error) {
 	if selector == "" {
 		selector = "html"
 	}
-	return c.handleAssertion("assert_text_not_visible", map[string]interface{}{
+	return s.handleAssertion(ctx, "assert_text_not_visible", map[string]interface{}{
 		"text": text, "selector": selector, "screenshot": screenshot,
 	})
 }
 
-func (c *Client) AssertTitle(title string, screenshot bool) (map[string]interface{}, error) {
-	return c.handleAssertion("assert_title", map[string]interface{}{
+func (s *BrowserSession) AssertTitle(title string, screenshot bool) (map[string]interface{}, error) {
+	return s.AssertTitleContext(context.Background(), title, screenshot)
+}
+
+func (s *BrowserSession) AssertTitleContext(ctx context.Context, title string, screenshot bool) (map[string]interface{}, error) {
+	return s.handleAssertion(ctx, "assert_title", map[string]interface{}{
 		"title": title, "screenshot": screenshot,
 	})
 }
 
-func (c *Client) AssertURL(urlSubstring string, screenshot bool) (map[string]interface{}, error) {
-	return c.handleAssertion("assert_url", map[string]interface{}{
+func (s *BrowserSession) AssertURL(urlSubstring string, screenshot bool) (map[string]interface{}, error) {
+	return s.AssertURLContext(context.Background(), urlSubstring, screenshot)
+}
+
+func (s *BrowserSession) AssertURLContext(ctx context.Context, urlSubstring string, screenshot bool) (map[string]interface{}, error) {
+	return s.handleAssertion(ctx, "assert_url", map[string]interface{}{
 		"url": urlSubstring, "screenshot": screenshot,
 	})
 }
 
-func (c *Client) AssertAttribute(selector, attribute, value string, screenshot bool) (map[string]interface{}, error) {
-	return c.handleAssertion("assert_attribute", map[string]interface{}{
+func (s *BrowserSession) AssertAttribute(selector, attribute, value string, screenshot bool) (map[string]interface{}, error) {
+	return s.AssertAttributeContext(context.Background(), selector, attribute, value, screenshot)
+}
+
+func (s *BrowserSession) AssertAttributeContext(ctx context.Context, selector, attribute, value string, screenshot bool) (map[string]interface{}, error) {
+	return s.handleAssertion(ctx, "assert_attribute", map[string]interface{}{
 		"selector": selector, "attribute": attribute, "value": value, "screenshot": screenshot,
 	})
 }
@@ -710,7 +1162,7 @@ func (c *Client) AssertAttribute(selector, attribute, value string, screenshot b
 // --- Helpers ---
 
 // saveBase64Result is a private helper to decode and save files returned by the browser
-func (c *Client) saveBase64Result(res map[string]interface{}, keyName, outputPath string) (map[string]interface{}, error) {
+func (s *BrowserSession) saveBase64Result(res map[string]interface{}, keyName, outputPath string) (map[string]interface{}, error) {
 	if status, ok := res["status"].(string); ok && status == "ok" {
 		if val, ok := res[keyName]; ok {
 			if b64, ok := val.(string); ok {
@@ -0,0 +1,142 @@
+package isoautomate
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SessionDescriptor is a serializable snapshot of a live session: what a
+// process needs to keep driving the browser after a restart, or to take it
+// over from another process (see BrowserSession.Descriptor, Detach and
+// Client.Attach). It holds the lease and profile lock tokens, so whoever
+// has it can control the browser; store it like a credential.
+type SessionDescriptor struct {
+	Session
+	InitSent    bool         `json:"init_sent"`
+	Lease       Lease        `json:"lease"`
+	ProfileLock *ProfileLock `json:"profile_lock,omitempty"`
+}
+
+// Descriptor returns a descriptor of the session, which stays active. Save
+// it to reattach after a crash; to hand the session to another process, use
+// Detach instead so only one process drives the browser.
+func (s *BrowserSession) Descriptor() (SessionDescriptor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.descriptorLocked()
+}
+
+// Detach stops using the session without releasing the browser and returns
+// its descriptor. The lease and profile lock are no longer renewed, so the
+// process that attaches must do so within Config.LeaseTTL.
+func (s *BrowserSession) Detach() (SessionDescriptor, error) {
+	s.mu.Lock()
+	d, err := s.descriptorLocked()
+	if err != nil {
+		s.mu.Unlock()
+		return d, err
+	}
+	k, lock, span := s.keepalive, s.profileLock, s.span
+	s.keepalive, s.profileLock, s.span = nil, nil, nil
+	s.Session = nil
+	s.mu.Unlock()
+	s.client.untrack(s)
+
+	if span != nil {
+		span.AddEvent("detached")
+		span.End()
+	}
+
+	if k != nil {
+		k.stop()
+	}
+	if lock != nil {
+		lock.stop()
+	}
+	s.client.logger.LogAttrs(context.Background(), slog.LevelInfo, "session detached", sessionAttrs(&d.Session)...)
+	return d, nil
+}
+
+// descriptorLocked builds the session's descriptor. s.mu must be held.
+func (s *BrowserSession) descriptorLocked() (SessionDescriptor, error) {
+	if s.Session == nil || s.keepalive == nil {
+		return SessionDescriptor{}, newError(ErrNotAcquired, nil, "No active session")
+	}
+	lease, err := s.keepalive.current()
+	if err != nil {
+		return SessionDescriptor{}, newError(ErrLeaseLost, err, "Lease on browser '%s' was lost", lease.BrowserID)
+	}
+
+	d := SessionDescriptor{Session: *s.Session, InitSent: s.InitSent, Lease: lease}
+	if s.profileLock != nil {
+		s.profileLock.mu.Lock()
+		lock := s.profileLock.lock
+		s.profileLock.mu.Unlock()
+		d.ProfileLock = &lock
+	}
+	return d, nil
+}
+
+// Attach resumes a session from its descriptor, e.g. after the process that
+// acquired it restarted. It checks that the browser is still busy on its
+// worker and takes over the lease (and profile lock). Like Acquire, it
+// fills the client's default session if that holds no browser.
+func (c *Client) Attach(d SessionDescriptor) (*BrowserSession, error) {
+	return c.AttachContext(context.Background(), d)
+}
+
+// AttachContext is like Attach but honours ctx for the Redis calls.
+func (c *Client) AttachContext(ctx context.Context, d SessionDescriptor) (*BrowserSession, error) {
+	if d.BrowserID == "" || d.WorkerName == "" || d.BrowserType == "" || d.Lease.Token == "" {
+		return nil, NewBrowserError("Incomplete session descriptor")
+	}
+	lease := d.Lease
+	lease.Worker, lease.BrowserType, lease.BrowserID = d.WorkerName, d.BrowserType, d.BrowserID
+
+	// 1. The browser must not have been released or reclaimed meanwhile
+	busy, err := c.transport.BrowserBusy(ctx, d.WorkerName, d.BrowserType, d.BrowserID)
+	if err != nil {
+		return nil, newError(nil, err, "Failed to attach session: %v", err)
+	}
+	if !busy {
+		return nil, newError(ErrLeaseLost, nil, "Browser '%s' is no longer busy on worker '%s'", d.BrowserID, d.WorkerName)
+	}
+
+	// 2. Take over the lease; this fails if someone else holds it now
+	if lease.Expires, err = c.transport.RenewLease(ctx, lease, c.leaseTTL); err != nil {
+		if errors.Is(err, ErrLeaseLost) {
+			return nil, newError(ErrLeaseLost, nil, "Lease on browser '%s' was lost", d.BrowserID)
+		}
+		return nil, newError(nil, err, "Failed to attach session: %v", err)
+	}
+
+	// 3. Take over the profile lock, or take it again if it expired
+	var lock *profileLockKeeper
+	if d.ProfileLock != nil {
+		held := *d.ProfileLock
+		held.Expires, err = c.transport.RenewProfileLock(ctx, held, c.leaseTTL)
+		if errors.Is(err, ErrLeaseLost) {
+			held, err = c.transport.LockProfile(ctx, held, c.leaseTTL)
+		}
+		if errors.Is(err, ErrProfileLocked) {
+			return nil, newError(ErrProfileLocked, nil, "Profile '%s' was taken by %s", d.ProfileName, held.Holder)
+		}
+		if err != nil {
+			return nil, newError(nil, err, "Failed to lock profile '%s': %v", d.ProfileName, err)
+		}
+		lock = c.startProfileLock(held, c.leaseTTL)
+	}
+
+	sess := d.Session
+	_, span := c.tracer.Start(ctx, "isoautomate.session", trace.WithAttributes(sessionSpanAttrs(&sess)...))
+	span.AddEvent("attached")
+
+	s := c.claimSession()
+	s.start(&sess, d.InitSent, c.startKeepalive(lease, c.leaseTTL), lock, span)
+	c.logger.LogAttrs(ctx, slog.LevelInfo, "session attached", sessionAttrs(&sess)...)
+	return s, nil
+}
@@ -0,0 +1,260 @@
+package isoautomate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// StepStatus is the outcome of a single step in a batch.
+type StepStatus string
+
+const (
+	StepOK      StepStatus = "ok"
+	StepFailed  StepStatus = "error"
+	StepSkipped StepStatus = "skipped" // Not run because an earlier step failed
+)
+
+// BatchStep is one queued action, serialized into the "steps" argument of
+// the batch task.
+type BatchStep struct {
+	Action string                 `json:"action"`
+	Args   map[string]interface{} `json:"args,omitempty"`
+}
+
+// StepResult is the outcome of one batch step.
+type StepResult struct {
+	Index    int
+	Action   string
+	Status   StepStatus
+	Err      error                  // *ActionError for failed steps, nil otherwise
+	Response map[string]interface{} // The step's own response, as a single action would return it
+}
+
+// DecodeStep decodes a step's response into T (e.g. StringResult for a
+// GetText step), like SendTyped does for a single action.
+func DecodeStep[T any](step StepResult) (T, error) {
+	var out T
+	if step.Err != nil {
+		return out, step.Err
+	}
+	raw, err := json.Marshal(step.Response)
+	if err != nil {
+		return out, newError(ErrInvalidResponse, err, "Failed to re-encode step %d: %v", step.Index, err)
+	}
+	return decodeResponse[T](step.Action, raw)
+}
+
+// BatchResult holds the ordered outcomes of a batch, one per queued step.
+type BatchResult struct {
+	Steps []StepResult
+}
+
+// Err returns the failed steps' errors joined together, or nil.
+func (r *BatchResult) Err() error {
+	var errs []error
+	for _, step := range r.Steps {
+		if step.Err != nil {
+			errs = append(errs, step.Err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Batch queues several actions and sends them to the worker as a single
+// "batch" task, saving one Redis round-trip per action. Steps run in the
+// order they were added. Build one with BrowserSession.Batch.
+type Batch struct {
+	s               *BrowserSession
+	steps           []BatchStep
+	continueOnError bool
+}
+
+// Batch starts an empty batch. By default the worker stops at the first
+// failing step and the remaining steps are reported as skipped.
+func (s *BrowserSession) Batch() *Batch {
+	return &Batch{s: s}
+}
+
+// ContinueOnError makes the worker run every step even if some fail.
+func (b *Batch) ContinueOnError() *Batch {
+	b.continueOnError = true
+	return b
+}
+
+// StopOnError restores the default: stop at the first failing step.
+func (b *Batch) StopOnError() *Batch {
+	b.continueOnError = false
+	return b
+}
+
+// Len returns the number of queued steps.
+func (b *Batch) Len() int {
+	return len(b.steps)
+}
+
+// Add queues any action by name, with the same arguments Send would take.
+func (b *Batch) Add(action string, args map[string]interface{}) *Batch {
+	b.steps = append(b.steps, BatchStep{Action: action, Args: args})
+	return b
+}
+
+// Run sends the batch and waits for every step. The wait is DefaultRPCWait
+// per step; use ctx for a tighter bound.
+func (b *Batch) Run(ctx context.Context) (*BatchResult, error) {
+	return b.RunWithTimeout(ctx, time.Duration(len(b.steps))*DefaultRPCWait)
+}
+
+// RunWithTimeout is Run with a custom wait for the whole batch.
+// The returned error is non-nil if the batch could not run or any step failed;
+// the result is still returned in the latter case. An empty batch is not
+// sent and yields an empty result.
+func (b *Batch) RunWithTimeout(ctx context.Context, timeout time.Duration) (*BatchResult, error) {
+	if len(b.steps) == 0 {
+		return &BatchResult{Steps: []StepResult{}}, nil
+	}
+
+	raw, err := b.s.sendRaw(ctx, "batch", map[string]interface{}{
+		"steps":         b.steps,
+		"stop_on_error": !b.continueOnError,
+	}, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	// The batch status is "error" whenever a step failed, so it is judged
+	// per step below rather than through decodeMap.
+	var res map[string]interface{}
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return nil, newError(ErrInvalidResponse, err, "Failed to parse worker response: %v", err)
+	}
+
+	rawResults, _ := res["results"].([]interface{})
+	if len(rawResults) == 0 {
+		// No per-step results: the batch itself was rejected.
+		if err := actionError("batch", res); err != nil {
+			return nil, err
+		}
+		return nil, newError(ErrInvalidResponse, nil, "Batch response has no step results")
+	}
+	if len(rawResults) > len(b.steps) {
+		return nil, newError(ErrInvalidResponse, nil, "Batch returned %d results for %d steps", len(rawResults), len(b.steps))
+	}
+
+	out := &BatchResult{Steps: make([]StepResult, len(b.steps))}
+	for i, step := range b.steps {
+		result := StepResult{Index: i, Action: step.Action, Status: StepSkipped}
+		if i < len(rawResults) {
+			stepRes, ok := rawResults[i].(map[string]interface{})
+			if !ok {
+				return nil, newError(ErrInvalidResponse, nil, "Batch step %d result is not an object", i)
+			}
+			result.Response = stepRes
+			result.Status = StepOK
+			if stepErr := actionError(step.Action, stepRes); stepErr != nil {
+				result.Status = StepFailed
+				result.Err = stepErr
+			}
+		}
+		out.Steps[i] = result
+	}
+	return out, out.Err()
+}
+
+// --- Queued Actions ---
+// These mirror the BrowserSession methods of the same name.
+
+func (b *Batch) OpenURL(url string) *Batch {
+	return b.Add("open_url", map[string]interface{}{"url": url})
+}
+
+func (b *Batch) Click(selector string, timeout int) *Batch {
+	args := map[string]interface{}{"selector": selector}
+	if timeout > 0 {
+		args["timeout"] = timeout
+	}
+	return b.Add("click", args)
+}
+
+func (b *Batch) ClickIfVisible(selector string) *Batch {
+	return b.Add("click_if_visible", map[string]interface{}{"selector": selector})
+}
+
+func (b *Batch) Type(selector, text string, timeout int) *Batch {
+	args := map[string]interface{}{"selector": selector, "text": text}
+	if timeout > 0 {
+		args["timeout"] = timeout
+	}
+	return b.Add("type", args)
+}
+
+func (b *Batch) PressKeys(selector, text string) *Batch {
+	return b.Add("press_keys", map[string]interface{}{"selector": selector, "text": text})
+}
+
+func (b *Batch) SetValue(selector, text string) *Batch {
+	return b.Add("set_value", map[string]interface{}{"selector": selector, "text": text})
+}
+
+func (b *Batch) Clear(selector string) *Batch {
+	return b.Add("clear", map[string]interface{}{"selector": selector})
+}
+
+func (b *Batch) Submit(selector string) *Batch {
+	return b.Add("submit", map[string]interface{}{"selector": selector})
+}
+
+func (b *Batch) Focus(selector string) *Batch {
+	return b.Add("focus", map[string]interface{}{"selector": selector})
+}
+
+func (b *Batch) SelectOptionByText(selector, text string) *Batch {
+	return b.Add("select_option_by_text", map[string]interface{}{"selector": selector, "text": text})
+}
+
+func (b *Batch) SelectOptionByValue(selector, value string) *Batch {
+	return b.Add("select_option_by_value", map[string]interface{}{"selector": selector, "value": value})
+}
+
+func (b *Batch) ScrollIntoView(selector string) *Batch {
+	return b.Add("scroll_into_view", map[string]interface{}{"selector": selector})
+}
+
+func (b *Batch) Sleep(seconds float64) *Batch {
+	return b.Add("sleep", map[string]interface{}{"seconds": seconds})
+}
+
+func (b *Batch) WaitForElement(selector string, timeout int) *Batch {
+	args := map[string]interface{}{"selector": selector}
+	if timeout > 0 {
+		args["timeout"] = timeout
+	}
+	return b.Add("wait_for_element", args)
+}
+
+func (b *Batch) WaitForText(text, selector string, timeout int) *Batch {
+	if selector == "" {
+		selector = "html"
+	}
+	args := map[string]interface{}{"text": text, "selector": selector}
+	if timeout > 0 {
+		args["timeout"] = timeout
+	}
+	return b.Add("wait_for_text", args)
+}
+
+func (b *Batch) GetText(selector string) *Batch {
+	if selector == "" {
+		selector = "body"
+	}
+	return b.Add("get_text", map[string]interface{}{"selector": selector})
+}
+
+func (b *Batch) GetTitle() *Batch {
+	return b.Add("get_title", nil)
+}
+
+func (b *Batch) GetCurrentURL() *Batch {
+	return b.Add("get_current_url", nil)
+}
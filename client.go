@@ -3,94 +3,125 @@ package isoautomate
 import (
 	"context"
 	"crypto/tls"
-	"os"
-	"strconv"
+	"log/slog"
+	"net"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// Client is the main entry point for the SDK.
+// Client is the main entry point for the SDK. It holds the connection pool
+// shared by every session acquired through it.
+//
+// The embedded BrowserSession is the default session. Its fields and action
+// methods are promoted, so single-session code can call client.OpenURL
+// directly. It is always the same *BrowserSession: Acquire (or Attach) fills
+// it when it holds no browser and returns it, and returns independent
+// sessions while it is in use. Code driving several browsers at once should
+// use the sessions Acquire returns instead.
 type Client struct {
-	R           *redis.Client          // Redis Connection
-	Session     *Session               // Active Session Data
-	SessionData map[string]interface{} // Metadata from Release
-	VideoURL    string
-	RecordURL   string
-	InitSent    bool // Tracks if we've sent the first command
-
-	// Context for Redis operations
-	ctx context.Context
-}
+	*BrowserSession
 
-// New creates a new Client instance and connects to Redis.
-func New(cfg Config) (*Client, error) {
-	// 1. Load Environment Variables
-	LoadEnv(cfg.EnvFile)
+	transport Transport       // Task queues and browser accounting (Redis by default)
+	keys      keyspace        // Names the result keys, in the transport's namespace
+	retry     RetryPolicy     // How transient transport failures are retried
+	leaseTTL  time.Duration   // Lifetime of a browser lease between renewals
+	strategy  Strategy        // Default worker selection strategy
+	logger    *slog.Logger    // Task and lifecycle events (discarded by default)
+	tracer    trace.Tracer    // Session and task spans
+	metrics   MetricsRecorder // Task and session metrics (nil records nothing)
 
-	// 2. Resolve Config (Env vars override defaults, explicit config overrides env)
-	host := cfg.RedisHost
-	if host == "" {
-		host = os.Getenv("REDIS_HOST")
-	}
+	mu             sync.Mutex                   // Guards defaultClaimed and sessions
+	defaultClaimed bool                         // An Acquire or Attach is filling or holds the default session
+	sessions       map[*BrowserSession]struct{} // Sessions acquired or attached and not released yet
+}
 
-	port := cfg.RedisPort
-	if port == "" {
-		port = os.Getenv("REDIS_PORT")
-	}
-	if port == "" {
-		port = "6379" // Default
+// New creates a new Client instance and connects to Redis. cfg is resolved
+// against the env vars, the config file and the defaults first (see
+// Config.Resolve); an unusable configuration fails with a *ConfigError
+// listing every problem.
+func New(cfg Config) (*Client, error) {
+	// 1. Resolve Config (explicit config overrides env, env overrides the file)
+	cfg, err := cfg.Resolve()
+	if err != nil {
+		return nil, err
 	}
 
-	password := cfg.RedisPassword
-	if password == "" {
-		password = os.Getenv("REDIS_PASSWORD")
+	// 2. A custom transport replaces the Redis connection entirely
+	if cfg.Transport != nil {
+		c := NewWithTransport(cfg.Transport)
+		c.applyConfig(cfg)
+		return c, nil
 	}
 
-	db := cfg.RedisDB // Default is 0, which is fine
-	if db == 0 && os.Getenv("REDIS_DB") != "" {
-		d, _ := strconv.Atoi(os.Getenv("REDIS_DB"))
-		db = d
-	}
+	db := *cfg.RedisDB
+	tlsOpts := cfg.tlsSettings()
 
-	// SSL/TLS Logic
-	useSSL := cfg.RedisSSL
-	if !useSSL && (os.Getenv("REDIS_SSL") == "true" || os.Getenv("REDIS_SSL") == "1") {
-		useSSL = true
+	var tlsConfig *tls.Config
+	if cfg.RedisSSL || tlsOpts.configured() {
+		if tlsConfig, err = tlsOpts.build(); err != nil {
+			return nil, newError(nil, err, "Invalid Redis TLS configuration: %v", err)
+		}
 	}
 
 	// 3. Setup Redis Options
-	var rdb *redis.Client
+	var rdb redis.UniversalClient
 
-	// If a full URL is provided
-	if cfg.RedisURL != "" || os.Getenv("REDIS_URL") != "" {
-		url := cfg.RedisURL
-		if url == "" {
-			url = os.Getenv("REDIS_URL")
-		}
-		opts, err := redis.ParseURL(url)
+	if len(cfg.RedisClusterAddrs) > 0 {
+		// Redis Cluster
+		rdb = redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:     cfg.RedisClusterAddrs,
+			Password:  cfg.RedisPassword,
+			TLSConfig: tlsConfig,
+		})
+	} else if cfg.RedisMasterName != "" {
+		// Redis Sentinel
+		rdb = redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       cfg.RedisMasterName,
+			SentinelAddrs:    cfg.RedisSentinelAddrs,
+			SentinelPassword: cfg.RedisSentinelPassword,
+			Password:         cfg.RedisPassword,
+			DB:               db,
+			TLSConfig:        tlsConfig,
+		})
+	} else if cfg.RedisURL != "" {
+		// If a full URL is provided
+		opts, err := redis.ParseURL(cfg.RedisURL)
 		if err != nil {
-			return nil, NewBrowserError("Invalid Redis URL: %v", err)
+			return nil, newError(nil, err, "Invalid Redis URL: %v", err)
+		}
+		if opts.TLSConfig != nil {
+			// rediss:// gets the same TLS settings; "skip_verify=true" in
+			// the URL counts as an explicit opt-in too.
+			tc, err := tlsOpts.build()
+			if err != nil {
+				return nil, newError(nil, err, "Invalid Redis TLS configuration: %v", err)
+			}
+			if tc.ServerName == "" {
+				tc.ServerName = opts.TLSConfig.ServerName
+			}
+			tc.InsecureSkipVerify = tc.InsecureSkipVerify || opts.TLSConfig.InsecureSkipVerify
+			opts.TLSConfig = tc
+		} else if tlsConfig != nil {
+			// RedisSSL or a CA/client certificate upgrades a redis:// URL to
+			// TLS rather than being ignored.
+			if tlsConfig.ServerName == "" {
+				tlsConfig.ServerName, _, _ = net.SplitHostPort(opts.Addr)
+			}
+			opts.TLSConfig = tlsConfig
 		}
 		rdb = redis.NewClient(opts)
 	} else {
 		// Manual Configuration
-		if host == "" {
-			return nil, NewBrowserError("Missing Redis Configuration (Host)")
-		}
-
-		redisOptions := &redis.Options{
-			Addr:     host + ":" + port,
-			Password: password,
-			DB:       db,
-		}
-
-		if useSSL {
-			redisOptions.TLSConfig = &tls.Config{
-				InsecureSkipVerify: true, // Common for internal Redis, adjust if needed
-			}
-		}
-		rdb = redis.NewClient(redisOptions)
+		rdb = redis.NewClient(&redis.Options{
+			Addr:      cfg.RedisHost + ":" + cfg.RedisPort,
+			Password:  cfg.RedisPassword,
+			DB:        db,
+			TLSConfig: tlsConfig,
+		})
 	}
 
 	// 4. Test Connection
@@ -98,12 +129,140 @@ func New(cfg Config) (*Client, error) {
 	defer cancel()
 
 	if err := rdb.Ping(ctx).Err(); err != nil {
-		return nil, NewBrowserError("Failed to connect to Redis: %v", err)
+		_ = rdb.Close()
+		return nil, newError(nil, err, "Failed to connect to Redis: %v", err)
 	}
 
-	return &Client{
-		R:           rdb,
-		ctx:         context.Background(),
-		SessionData: make(map[string]interface{}),
-	}, nil
+	transport, _ := NewRedisTransportWithNamespace(rdb, cfg.Namespace)
+	c := NewWithTransport(transport)
+	c.applyConfig(cfg)
+	return c, nil
+}
+
+// applyConfig copies the transport-independent settings from cfg.
+func (c *Client) applyConfig(cfg Config) {
+	c.retry = cfg.Retry
+	if cfg.LeaseTTL > 0 {
+		c.leaseTTL = cfg.LeaseTTL
+	}
+	if cfg.Strategy != "" {
+		c.strategy = cfg.Strategy
+	}
+	if cfg.Logger != nil {
+		c.logger = cfg.Logger
+	}
+	if cfg.TracerProvider != nil {
+		c.SetTracerProvider(cfg.TracerProvider)
+	}
+	if cfg.Metrics != nil {
+		c.metrics = cfg.Metrics
+	}
+}
+
+// NewWithTransport creates a Client on top of an existing Transport
+// (e.g. a MemoryTransport in tests) with the default retry policy.
+// No connection check is made.
+func NewWithTransport(t Transport) *Client {
+	c := &Client{
+		transport: t,
+		retry:     DefaultRetryPolicy(),
+		leaseTTL:  DefaultLeaseTTL,
+		strategy:  StrategyRandom,
+		logger:    discardLogger,
+		tracer:    otel.GetTracerProvider().Tracer(tracerName),
+		sessions:  make(map[*BrowserSession]struct{}),
+	}
+	if rt, ok := t.(*RedisTransport); ok {
+		c.keys = rt.keys
+	} else {
+		c.keys = newKeyspace(DefaultNamespace, false)
+	}
+	c.BrowserSession = newBrowserSession(c)
+	return c
+}
+
+// Transport returns the transport the client sends tasks through.
+func (c *Client) Transport() Transport {
+	return c.transport
+}
+
+// Close releases the underlying transport (the Redis connection pool).
+// It does not release acquired browsers; call Release on each session (or
+// ReleaseAll) first.
+func (c *Client) Close() error {
+	return c.transport.Close()
+}
+
+// Sessions returns every session acquired or attached through the client
+// that has not been released or detached yet.
+func (c *Client) Sessions() []*BrowserSession {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	sessions := make([]*BrowserSession, 0, len(c.sessions))
+	for s := range c.sessions {
+		sessions = append(sessions, s)
+	}
+	return sessions
+}
+
+// ReleaseAll releases every session of the client concurrently, finalizing
+// video and recordings as Release does, until ctx is done. Sessions that
+// could not be released are reported in a *ReleaseError.
+func (c *Client) ReleaseAll(ctx context.Context) error {
+	return releaseAll(ctx, c.Sessions())
+}
+
+// claimSession returns the default session if no session holds it, or a
+// new one, for an Acquire or Attach to fill. A failed fill must hand the
+// default session back with unclaimSession.
+func (c *Client) claimSession() *BrowserSession {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.defaultClaimed {
+		return newBrowserSession(c)
+	}
+	c.defaultClaimed = true
+	return c.BrowserSession
+}
+
+// unclaimSession hands back a session from claimSession that was not filled.
+func (c *Client) unclaimSession(s *BrowserSession) {
+	if s != c.BrowserSession {
+		return
+	}
+	c.mu.Lock()
+	c.defaultClaimed = false
+	c.mu.Unlock()
+}
+
+// track records a new session of the client.
+func (c *Client) track(s *BrowserSession) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.sessions[s]; !ok {
+		c.sessions[s] = struct{}{}
+		if c.metrics != nil {
+			c.metrics.SessionsHeld(1)
+		}
+	}
+}
+
+// untrack forgets a released or detached session; the default session is
+// free for the next Acquire again.
+func (c *Client) untrack(s *BrowserSession) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.sessions[s]; ok {
+		delete(c.sessions, s)
+		if c.metrics != nil {
+			c.metrics.SessionsHeld(-1)
+		}
+	}
+	if s == c.BrowserSession {
+		c.defaultClaimed = false
+	}
 }
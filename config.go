@@ -1,10 +1,22 @@
 package isoautomate
 
-import "os"
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+	"time"
 
-// Constants defining the Protocol
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Constants defining the Protocol. RedisPrefix and WorkersSet are the keys of
+// the default namespace. On Redis Cluster the namespace is used hash-tagged,
+// as "{ISOAUTOMATE}:", so that all fleet keys share one slot.
 const (
-	RedisPrefix      = "ISOAUTOMATE:"
+	DefaultNamespace = "ISOAUTOMATE"
+	RedisPrefix      = DefaultNamespace + ":"
 	WorkersSet       = RedisPrefix + "workers"
 	ScreenshotFolder = "screenshots"
 )
@@ -12,13 +24,179 @@ const (
 // AssertionFolder is determined at runtime
 var AssertionFolder = ScreenshotFolder + string(os.PathSeparator) + "failures"
 
-// Config holds the connection details
+// Config holds the connection details. New resolves it in layers: fields
+// set here win over env vars, which win over the config file, which wins
+// over the defaults (see Resolve). Bool fields can only switch a setting on.
+// The endpoint (RedisURL, RedisHost and RedisPort, Sentinel or Cluster) is
+// taken whole from the highest layer that sets one, and only one kind may be
+// set.
 type Config struct {
 	RedisURL      string
 	RedisHost     string
-	RedisPort     string
+	RedisPort     string // Default "6379"
 	RedisPassword string
-	RedisDB       int
+	RedisDB       *int // nil is unset; use Ptr(0) to force DB 0 over REDIS_DB or the file
 	RedisSSL      bool
 	EnvFile       string // Custom path to .env file
+
+	// ConfigFile is a YAML, JSON or TOML file of settings (env
+	// ISOAUTOMATE_CONFIG), and Environment the named section of it to use on
+	// top of its top-level settings (env ISOAUTOMATE_ENV), e.g. "staging".
+	ConfigFile  string
+	Environment string
+
+	// TLS, used with RedisSSL, a rediss:// URL, or as soon as a CA or
+	// client certificate is set (even with a redis:// URL, which is then
+	// dialled over TLS). The server certificate is always verified
+	// (against RedisTLSCAFile, or the system roots) unless
+	// RedisTLSInsecureSkipVerify is set. Each field can also be set with the
+	// env var in its comment.
+	RedisTLSCAFile             string // REDIS_TLS_CA_FILE: PEM bundle of trusted CAs
+	RedisTLSCertFile           string // REDIS_TLS_CERT_FILE: client certificate, for mutual TLS
+	RedisTLSKeyFile            string // REDIS_TLS_KEY_FILE: client private key
+	RedisTLSServerName         string // REDIS_TLS_SERVER_NAME: name to verify instead of the host
+	RedisTLSMinVersion         string // REDIS_TLS_MIN_VERSION: "1.2" (default) or "1.3"
+	RedisTLSInsecureSkipVerify bool   // REDIS_TLS_INSECURE_SKIP_VERIFY: disables verification; testing only
+
+	// Sentinel: the master's name and the sentinels' "host:port" addresses
+	// (env REDIS_SENTINEL_MASTER, REDIS_SENTINEL_ADDRS, comma-separated).
+	// RedisPassword and RedisDB apply to the master.
+	RedisMasterName       string
+	RedisSentinelAddrs    []string
+	RedisSentinelPassword string // env REDIS_SENTINEL_PASSWORD
+
+	// Cluster: "host:port" seed nodes (env REDIS_CLUSTER_ADDRS,
+	// comma-separated). RedisDB must be 0.
+	RedisClusterAddrs []string
+
+	// Retry controls retries of transient Redis failures.
+	// Zero fields (and a nil Jitter) fall back to DefaultRetryPolicy.
+	Retry RetryPolicy
+
+	// LeaseTTL is how long an acquired browser stays reserved without a
+	// keepalive renewal (env ISOAUTOMATE_LEASE_TTL, default DefaultLeaseTTL,
+	// at least MinLeaseTTL).
+	LeaseTTL time.Duration
+
+	// Strategy is the default worker selection strategy for Acquire
+	// (env ISOAUTOMATE_STRATEGY, default StrategyRandom).
+	Strategy Strategy
+
+	// Namespace prefixes every Redis key, queue and result key of the fleet
+	// (env ISOAUTOMATE_NAMESPACE, default DefaultNamespace), so several
+	// fleets or tenants can share one Redis. Workers must use the same one.
+	// With a custom Transport, use NewRedisTransportWithNamespace instead.
+	Namespace string
+
+	// Logger receives the client's task and lifecycle logs (see
+	// Client.SetLogger). nil keeps the SDK silent.
+	Logger *slog.Logger
+
+	// TracerProvider receives the client's session and task spans (see
+	// Client.SetTracerProvider). nil uses the global provider.
+	TracerProvider trace.TracerProvider
+
+	// Metrics records the client's task and session metrics (see
+	// Client.SetMetrics and the isometrics package). nil records nothing.
+	Metrics MetricsRecorder
+
+	// Transport, if set, is used instead of connecting to Redis; all Redis
+	// fields above are then ignored.
+	Transport Transport
+}
+
+// Ptr returns a pointer to v, for optional fields such as Config.RedisDB.
+func Ptr[T any](v T) *T {
+	return &v
+}
+
+// redacted replaces secrets in String.
+const redacted = "REDACTED"
+
+// String describes the set fields of cfg with passwords redacted, so a
+// Config can be logged safely.
+func (cfg Config) String() string {
+	var fields []string
+	add := func(name string, value interface{}) {
+		fields = append(fields, fmt.Sprintf("%s: %v", name, value))
+	}
+	addString := func(name, value string) {
+		if value != "" {
+			add(name, fmt.Sprintf("%q", value))
+		}
+	}
+	addList := func(name string, values []string) {
+		if len(values) > 0 {
+			add(name, fmt.Sprintf("%q", values))
+		}
+	}
+	addSecret := func(name, value string) {
+		if value != "" {
+			add(name, redacted)
+		}
+	}
+
+	if cfg.RedisURL != "" {
+		u, err := url.Parse(cfg.RedisURL)
+		if err != nil {
+			add("RedisURL", redacted)
+		} else {
+			add("RedisURL", fmt.Sprintf("%q", u.Redacted()))
+		}
+	}
+	addString("RedisHost", cfg.RedisHost)
+	addString("RedisPort", cfg.RedisPort)
+	addSecret("RedisPassword", cfg.RedisPassword)
+	if cfg.RedisDB != nil {
+		add("RedisDB", *cfg.RedisDB)
+	}
+	if cfg.RedisSSL {
+		add("RedisSSL", true)
+	}
+	addString("EnvFile", cfg.EnvFile)
+	addString("ConfigFile", cfg.ConfigFile)
+	addString("Environment", cfg.Environment)
+	addString("RedisTLSCAFile", cfg.RedisTLSCAFile)
+	addString("RedisTLSCertFile", cfg.RedisTLSCertFile)
+	addString("RedisTLSKeyFile", cfg.RedisTLSKeyFile)
+	addString("RedisTLSServerName", cfg.RedisTLSServerName)
+	addString("RedisTLSMinVersion", cfg.RedisTLSMinVersion)
+	if cfg.RedisTLSInsecureSkipVerify {
+		add("RedisTLSInsecureSkipVerify", true)
+	}
+	addString("RedisMasterName", cfg.RedisMasterName)
+	addList("RedisSentinelAddrs", cfg.RedisSentinelAddrs)
+	addSecret("RedisSentinelPassword", cfg.RedisSentinelPassword)
+	addList("RedisClusterAddrs", cfg.RedisClusterAddrs)
+	if r := cfg.Retry; r != (RetryPolicy{}) {
+		jitter := "default"
+		if r.Jitter != nil {
+			jitter = fmt.Sprint(*r.Jitter)
+		}
+		add("Retry", fmt.Sprintf("{MaxAttempts:%d InitialBackoff:%s MaxBackoff:%s Multiplier:%g Jitter:%s MaxElapsed:%s}",
+			r.MaxAttempts, r.InitialBackoff, r.MaxBackoff, r.Multiplier, jitter, r.MaxElapsed))
+	}
+	if cfg.LeaseTTL != 0 {
+		add("LeaseTTL", cfg.LeaseTTL)
+	}
+	addString("Strategy", string(cfg.Strategy))
+	addString("Namespace", cfg.Namespace)
+	if cfg.Logger != nil {
+		add("Logger", "set")
+	}
+	if cfg.TracerProvider != nil {
+		add("TracerProvider", fmt.Sprintf("%T", cfg.TracerProvider))
+	}
+	if cfg.Metrics != nil {
+		add("Metrics", "set")
+	}
+	if cfg.Transport != nil {
+		add("Transport", fmt.Sprintf("%T", cfg.Transport))
+	}
+	return "Config{" + strings.Join(fields, ", ") + "}"
+}
+
+// GoString redacts %#v the same way as String.
+func (cfg Config) GoString() string {
+	return "isoautomate." + cfg.String()
 }
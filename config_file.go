@@ -0,0 +1,468 @@
+package isoautomate
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/redis/go-redis/v9"
+	"gopkg.in/yaml.v3"
+)
+
+// settings is one layer of configuration: the config file, the env vars or
+// the explicit Config. Zero fields are unset in their layer; for the
+// pointers, nil is unset.
+type settings struct {
+	RedisURL                   string        `json:"redis_url" yaml:"redis_url" toml:"redis_url"`
+	RedisHost                  string        `json:"redis_host" yaml:"redis_host" toml:"redis_host"`
+	RedisPort                  portString    `json:"redis_port" yaml:"redis_port" toml:"redis_port"`
+	RedisPassword              string        `json:"redis_password" yaml:"redis_password" toml:"redis_password"`
+	RedisDB                    *int          `json:"redis_db" yaml:"redis_db" toml:"redis_db"`
+	RedisSSL                   *bool         `json:"redis_ssl" yaml:"redis_ssl" toml:"redis_ssl"`
+	RedisTLSCAFile             string        `json:"redis_tls_ca_file" yaml:"redis_tls_ca_file" toml:"redis_tls_ca_file"`
+	RedisTLSCertFile           string        `json:"redis_tls_cert_file" yaml:"redis_tls_cert_file" toml:"redis_tls_cert_file"`
+	RedisTLSKeyFile            string        `json:"redis_tls_key_file" yaml:"redis_tls_key_file" toml:"redis_tls_key_file"`
+	RedisTLSServerName         string        `json:"redis_tls_server_name" yaml:"redis_tls_server_name" toml:"redis_tls_server_name"`
+	RedisTLSMinVersion         string        `json:"redis_tls_min_version" yaml:"redis_tls_min_version" toml:"redis_tls_min_version"`
+	RedisTLSInsecureSkipVerify *bool         `json:"redis_tls_insecure_skip_verify" yaml:"redis_tls_insecure_skip_verify" toml:"redis_tls_insecure_skip_verify"`
+	RedisSentinelMaster        string        `json:"redis_sentinel_master" yaml:"redis_sentinel_master" toml:"redis_sentinel_master"`
+	RedisSentinelAddrs         []string      `json:"redis_sentinel_addrs" yaml:"redis_sentinel_addrs" toml:"redis_sentinel_addrs"`
+	RedisSentinelPassword      string        `json:"redis_sentinel_password" yaml:"redis_sentinel_password" toml:"redis_sentinel_password"`
+	RedisClusterAddrs          []string      `json:"redis_cluster_addrs" yaml:"redis_cluster_addrs" toml:"redis_cluster_addrs"`
+	Namespace                  string        `json:"namespace" yaml:"namespace" toml:"namespace"`
+	LeaseTTL                   duration      `json:"lease_ttl" yaml:"lease_ttl" toml:"lease_ttl"`
+	Strategy                   Strategy      `json:"strategy" yaml:"strategy" toml:"strategy"`
+	Retry                      retrySettings `json:"retry" yaml:"retry" toml:"retry"`
+}
+
+// retrySettings is the file form of RetryPolicy.
+type retrySettings struct {
+	MaxAttempts    int      `json:"max_attempts" yaml:"max_attempts" toml:"max_attempts"`
+	InitialBackoff duration `json:"initial_backoff" yaml:"initial_backoff" toml:"initial_backoff"`
+	MaxBackoff     duration `json:"max_backoff" yaml:"max_backoff" toml:"max_backoff"`
+	Multiplier     float64  `json:"multiplier" yaml:"multiplier" toml:"multiplier"`
+	Jitter         *float64 `json:"jitter" yaml:"jitter" toml:"jitter"`
+	MaxElapsed     duration `json:"max_elapsed" yaml:"max_elapsed" toml:"max_elapsed"`
+}
+
+// configFile is the layout of Config.ConfigFile: settings shared by every
+// environment at the top level, and named environments that override them.
+type configFile struct {
+	settings     `yaml:",inline"`
+	Environments map[string]settings `json:"environments" yaml:"environments" toml:"environments"`
+}
+
+// duration is a time.Duration written as "30s" or "1m30s" in config files.
+type duration time.Duration
+
+func (d *duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = duration(v)
+	return nil
+}
+
+// portString accepts the port as a number or a string in config files.
+type portString string
+
+func (p *portString) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	return p.set(v)
+}
+
+func (p *portString) UnmarshalTOML(v interface{}) error {
+	return p.set(v)
+}
+
+func (p *portString) set(v interface{}) error {
+	switch v := v.(type) {
+	case string:
+		*p = portString(v)
+	case float64:
+		*p = portString(strconv.FormatFloat(v, 'f', -1, 64))
+	case int64:
+		*p = portString(strconv.FormatInt(v, 10))
+	default:
+		return fmt.Errorf("redis_port must be a number or a string, not %T", v)
+	}
+	return nil
+}
+
+// merge sets every field of s that is set in over, including the fields of
+// Retry one by one. The endpoint settings (URL, host and port, Sentinel,
+// Cluster) go as a group: if over picks an endpoint at all, the one in s is
+// dropped first, so a lower layer's redis_url cannot outrank a higher
+// layer's redis_host. A port on its own still applies to the host below.
+func (s *settings) merge(over settings) {
+	if over.setsEndpoint() {
+		d := defaultSettings()
+		s.RedisURL, s.RedisHost, s.RedisPort = d.RedisURL, d.RedisHost, d.RedisPort
+		s.RedisSentinelMaster, s.RedisSentinelAddrs, s.RedisSentinelPassword =
+			d.RedisSentinelMaster, d.RedisSentinelAddrs, d.RedisSentinelPassword
+		s.RedisClusterAddrs = d.RedisClusterAddrs
+	}
+	mergeFields(reflect.ValueOf(s).Elem(), reflect.ValueOf(over))
+}
+
+func (s settings) setsEndpoint() bool {
+	return s.RedisURL != "" || s.RedisHost != "" || s.RedisSentinelMaster != "" ||
+		len(s.RedisSentinelAddrs) > 0 || len(s.RedisClusterAddrs) > 0
+}
+
+func mergeFields(dst, src reflect.Value) {
+	for i := 0; i < src.NumField(); i++ {
+		f := src.Field(i)
+		if f.Kind() == reflect.Struct {
+			mergeFields(dst.Field(i), f)
+		} else if !f.IsZero() {
+			dst.Field(i).Set(f)
+		}
+	}
+}
+
+// defaultSettings is the bottom layer. Retry is left to
+// RetryPolicy.withDefaults.
+func defaultSettings() settings {
+	return settings{
+		RedisPort: "6379",
+		RedisDB:   Ptr(0),
+		Namespace: DefaultNamespace,
+		LeaseTTL:  duration(DefaultLeaseTTL),
+		Strategy:  StrategyRandom,
+	}
+}
+
+// envSettings reads the env var layer. Values that do not parse are
+// reported as problems and left unset.
+func envSettings() (settings, []string) {
+	var problems []string
+	flag := func(name string) *bool {
+		v := os.Getenv(name)
+		if v == "" {
+			return nil
+		}
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s '%s' is not true or false", name, v))
+			return nil
+		}
+		return &b
+	}
+
+	s := settings{
+		RedisURL:                   os.Getenv("REDIS_URL"),
+		RedisHost:                  os.Getenv("REDIS_HOST"),
+		RedisPort:                  portString(os.Getenv("REDIS_PORT")),
+		RedisPassword:              os.Getenv("REDIS_PASSWORD"),
+		RedisSSL:                   flag("REDIS_SSL"),
+		RedisTLSCAFile:             os.Getenv("REDIS_TLS_CA_FILE"),
+		RedisTLSCertFile:           os.Getenv("REDIS_TLS_CERT_FILE"),
+		RedisTLSKeyFile:            os.Getenv("REDIS_TLS_KEY_FILE"),
+		RedisTLSServerName:         os.Getenv("REDIS_TLS_SERVER_NAME"),
+		RedisTLSMinVersion:         os.Getenv("REDIS_TLS_MIN_VERSION"),
+		RedisTLSInsecureSkipVerify: flag("REDIS_TLS_INSECURE_SKIP_VERIFY"),
+		RedisSentinelMaster:        os.Getenv("REDIS_SENTINEL_MASTER"),
+		RedisSentinelAddrs:         splitList(os.Getenv("REDIS_SENTINEL_ADDRS")),
+		RedisSentinelPassword:      os.Getenv("REDIS_SENTINEL_PASSWORD"),
+		RedisClusterAddrs:          splitList(os.Getenv("REDIS_CLUSTER_ADDRS")),
+		Namespace:                  os.Getenv("ISOAUTOMATE_NAMESPACE"),
+		Strategy:                   Strategy(os.Getenv("ISOAUTOMATE_STRATEGY")),
+	}
+
+	if v := os.Getenv("REDIS_DB"); v != "" {
+		db, err := strconv.Atoi(v)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("REDIS_DB '%s' is not a number", v))
+		} else {
+			s.RedisDB = &db
+		}
+	}
+	if v := os.Getenv("ISOAUTOMATE_LEASE_TTL"); v != "" {
+		if err := s.LeaseTTL.UnmarshalText([]byte(v)); err != nil {
+			problems = append(problems, fmt.Sprintf("ISOAUTOMATE_LEASE_TTL '%s' is not a duration", v))
+		}
+	}
+	return s, problems
+}
+
+// explicitSettings is the top layer: the fields set in cfg.
+func explicitSettings(cfg Config) settings {
+	s := settings{
+		RedisURL:              cfg.RedisURL,
+		RedisHost:             cfg.RedisHost,
+		RedisPort:             portString(cfg.RedisPort),
+		RedisPassword:         cfg.RedisPassword,
+		RedisDB:               cfg.RedisDB,
+		RedisTLSCAFile:        cfg.RedisTLSCAFile,
+		RedisTLSCertFile:      cfg.RedisTLSCertFile,
+		RedisTLSKeyFile:       cfg.RedisTLSKeyFile,
+		RedisTLSServerName:    cfg.RedisTLSServerName,
+		RedisTLSMinVersion:    cfg.RedisTLSMinVersion,
+		RedisSentinelMaster:   cfg.RedisMasterName,
+		RedisSentinelAddrs:    cfg.RedisSentinelAddrs,
+		RedisSentinelPassword: cfg.RedisSentinelPassword,
+		RedisClusterAddrs:     cfg.RedisClusterAddrs,
+		Namespace:             cfg.Namespace,
+		LeaseTTL:              duration(cfg.LeaseTTL),
+		Strategy:              cfg.Strategy,
+		Retry: retrySettings{
+			MaxAttempts:    cfg.Retry.MaxAttempts,
+			InitialBackoff: duration(cfg.Retry.InitialBackoff),
+			MaxBackoff:     duration(cfg.Retry.MaxBackoff),
+			Multiplier:     cfg.Retry.Multiplier,
+			Jitter:         cfg.Retry.Jitter,
+			MaxElapsed:     duration(cfg.Retry.MaxElapsed),
+		},
+	}
+	if cfg.RedisSSL {
+		s.RedisSSL = Ptr(true)
+	}
+	if cfg.RedisTLSInsecureSkipVerify {
+		s.RedisTLSInsecureSkipVerify = Ptr(true)
+	}
+	return s
+}
+
+// config converts the merged layers back to a Config.
+func (s settings) config() Config {
+	return Config{
+		RedisURL:                   s.RedisURL,
+		RedisHost:                  s.RedisHost,
+		RedisPort:                  string(s.RedisPort),
+		RedisPassword:              s.RedisPassword,
+		RedisDB:                    s.RedisDB,
+		RedisSSL:                   s.RedisSSL != nil && *s.RedisSSL,
+		RedisTLSCAFile:             s.RedisTLSCAFile,
+		RedisTLSCertFile:           s.RedisTLSCertFile,
+		RedisTLSKeyFile:            s.RedisTLSKeyFile,
+		RedisTLSServerName:         s.RedisTLSServerName,
+		RedisTLSMinVersion:         s.RedisTLSMinVersion,
+		RedisTLSInsecureSkipVerify: s.RedisTLSInsecureSkipVerify != nil && *s.RedisTLSInsecureSkipVerify,
+		RedisMasterName:            s.RedisSentinelMaster,
+		RedisSentinelAddrs:         s.RedisSentinelAddrs,
+		RedisSentinelPassword:      s.RedisSentinelPassword,
+		RedisClusterAddrs:          s.RedisClusterAddrs,
+		Namespace:                  s.Namespace,
+		LeaseTTL:                   time.Duration(s.LeaseTTL),
+		Strategy:                   s.Strategy,
+		Retry: RetryPolicy{
+			MaxAttempts:    s.Retry.MaxAttempts,
+			InitialBackoff: time.Duration(s.Retry.InitialBackoff),
+			MaxBackoff:     time.Duration(s.Retry.MaxBackoff),
+			Multiplier:     s.Retry.Multiplier,
+			Jitter:         s.Retry.Jitter,
+			MaxElapsed:     time.Duration(s.Retry.MaxElapsed),
+		},
+	}
+}
+
+// loadConfigFile reads the top-level settings of a config file, with the
+// named environment on top if one is given. The format follows the file
+// extension; unknown settings are errors, to catch typos.
+func loadConfigFile(path, environment string) (settings, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return settings{}, fmt.Errorf("Failed to read config file: %w", err)
+	}
+
+	var f configFile
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err = dec.Decode(&f); errors.Is(err, io.EOF) {
+			err = nil // Empty file
+		}
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&f)
+	case ".toml":
+		var md toml.MetaData
+		if md, err = toml.Decode(string(data), &f); err == nil {
+			if undecoded := md.Undecoded(); len(undecoded) > 0 {
+				err = fmt.Errorf("unknown setting '%s'", undecoded[0])
+			}
+		}
+	default:
+		return settings{}, fmt.Errorf("Config file %s: unsupported format (use .yaml, .yml, .json or .toml)", path)
+	}
+	if err != nil {
+		return settings{}, fmt.Errorf("Invalid config file %s: %v", path, err)
+	}
+
+	s := f.settings
+	if environment != "" {
+		env, ok := f.Environments[environment]
+		if !ok {
+			names := make([]string, 0, len(f.Environments))
+			for name := range f.Environments {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return settings{}, fmt.Errorf("Config file %s has no environment '%s' (available: %s)",
+				path, environment, strings.Join(names, ", "))
+		}
+		s.merge(env)
+	}
+	return s, nil
+}
+
+// Resolve returns cfg with every layer applied, highest first: the fields
+// set in cfg, the env vars (after loading EnvFile or ./.env), the config
+// file's environment and then its top-level settings, and the defaults.
+// The Redis endpoint is taken whole from the highest layer that sets one.
+// The result is validated; a *ConfigError lists every problem found in any
+// layer. New calls Resolve itself.
+func (cfg Config) Resolve() (Config, error) {
+	LoadEnv(cfg.EnvFile)
+
+	env, problems := envSettings()
+	path := firstNonEmpty(cfg.ConfigFile, os.Getenv("ISOAUTOMATE_CONFIG"))
+	environment := firstNonEmpty(cfg.Environment, os.Getenv("ISOAUTOMATE_ENV"))
+
+	s := defaultSettings()
+	if path != "" {
+		file, err := loadConfigFile(path, environment)
+		if err != nil {
+			problems = append(problems, err.Error())
+		}
+		s.merge(file)
+	} else if environment != "" {
+		problems = append(problems, fmt.Sprintf("Environment '%s' is set without a ConfigFile", environment))
+	}
+	s.merge(env)
+	s.merge(explicitSettings(cfg))
+
+	resolved := s.config()
+	resolved.EnvFile = cfg.EnvFile
+	resolved.ConfigFile = path
+	resolved.Environment = environment
+	resolved.Logger = cfg.Logger
+	resolved.TracerProvider = cfg.TracerProvider
+	resolved.Metrics = cfg.Metrics
+	resolved.Transport = cfg.Transport
+
+	problems = append(problems, resolved.problems()...)
+	if len(problems) > 0 {
+		return resolved, &ConfigError{Problems: problems}
+	}
+	return resolved, nil
+}
+
+// Validate checks cfg as it stands, without the env vars or config file
+// (see Resolve), and reports every problem in a *ConfigError. Unset fields
+// are fine; they take their defaults.
+func (cfg Config) Validate() error {
+	if problems := cfg.problems(); len(problems) > 0 {
+		return &ConfigError{Problems: problems}
+	}
+	return nil
+}
+
+func (cfg Config) problems() []string {
+	var problems []string
+	add := func(format string, a ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, a...))
+	}
+
+	if cfg.Namespace != "" && !validNamespace(cfg.Namespace) {
+		add("Namespace '%s' must not contain '{', '}', ':' or whitespace", cfg.Namespace)
+	}
+	if cfg.Strategy != "" && !cfg.Strategy.valid() {
+		add("Strategy '%s' is not one of %s, %s, %s or %s", cfg.Strategy,
+			StrategyRandom, StrategyLeastBusy, StrategyRoundRobin, StrategyWeighted)
+	}
+	if cfg.LeaseTTL != 0 && cfg.LeaseTTL < MinLeaseTTL {
+		add("LeaseTTL %s must be at least %s", cfg.LeaseTTL, MinLeaseTTL)
+	}
+	r := cfg.Retry
+	if r.MaxAttempts < 0 || r.InitialBackoff < 0 || r.MaxBackoff < 0 || r.MaxElapsed < 0 {
+		add("Retry attempts and durations must not be negative")
+	}
+	if r.Multiplier != 0 && r.Multiplier < 1 {
+		add("Retry.Multiplier must be at least 1")
+	}
+	if r.Jitter != nil && (*r.Jitter < 0 || *r.Jitter > 1) {
+		add("Retry.Jitter must be between 0 and 1")
+	}
+
+	if cfg.Transport != nil {
+		return problems // The Redis fields are ignored
+	}
+
+	if cfg.RedisPort != "" {
+		if port, err := strconv.Atoi(cfg.RedisPort); err != nil || port < 1 || port > 65535 {
+			add("RedisPort '%s' is not a valid port", cfg.RedisPort)
+		}
+	}
+	if cfg.RedisDB != nil && *cfg.RedisDB < 0 {
+		add("RedisDB must not be negative")
+	}
+	if cfg.RedisURL != "" {
+		if _, err := redis.ParseURL(cfg.RedisURL); err != nil {
+			add("Invalid RedisURL: %v", err)
+		}
+	}
+	if len(cfg.RedisClusterAddrs) > 0 && cfg.RedisDB != nil && *cfg.RedisDB != 0 {
+		add("Redis Cluster only supports RedisDB 0")
+	}
+	if cfg.RedisMasterName != "" && len(cfg.RedisSentinelAddrs) == 0 {
+		add("Missing Redis Sentinel addresses for master '%s'", cfg.RedisMasterName)
+	}
+	if cfg.RedisMasterName == "" && len(cfg.RedisSentinelAddrs) > 0 {
+		add("RedisSentinelAddrs are set without RedisMasterName")
+	}
+	var modes []string
+	if cfg.RedisURL != "" {
+		modes = append(modes, "RedisURL")
+	}
+	if cfg.RedisHost != "" {
+		modes = append(modes, "RedisHost")
+	}
+	if cfg.RedisMasterName != "" || len(cfg.RedisSentinelAddrs) > 0 {
+		modes = append(modes, "Sentinel")
+	}
+	if len(cfg.RedisClusterAddrs) > 0 {
+		modes = append(modes, "Cluster")
+	}
+	if len(modes) > 1 {
+		add("Conflicting Redis connection settings: %s (set only one)", strings.Join(modes, ", "))
+	}
+	if len(modes) == 0 {
+		add("Missing Redis Configuration (set RedisURL, RedisHost, RedisMasterName or RedisClusterAddrs)")
+	}
+	if tlsOpts := cfg.tlsSettings(); tlsOpts.configured() || tlsOpts.minVersion != "" || tlsOpts.keyFile != "" {
+		if _, err := tlsOpts.build(); err != nil {
+			add("Invalid Redis TLS configuration: %v", err)
+		}
+	}
+	return problems
+}
+
+// tlsSettings returns the TLS fields of cfg.
+func (cfg Config) tlsSettings() tlsSettings {
+	return tlsSettings{
+		caFile:     cfg.RedisTLSCAFile,
+		certFile:   cfg.RedisTLSCertFile,
+		keyFile:    cfg.RedisTLSKeyFile,
+		serverName: cfg.RedisTLSServerName,
+		minVersion: cfg.RedisTLSMinVersion,
+		insecure:   cfg.RedisTLSInsecureSkipVerify,
+	}
+}
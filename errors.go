@@ -1,19 +1,161 @@
 package isoautomate
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Sentinel errors. Every error returned by the SDK can be matched against
+// these with errors.Is; the underlying Redis, JSON or context error stays
+// wrapped alongside them.
+var (
+	// ErrNotAcquired is returned when an action is sent without an acquired session.
+	ErrNotAcquired = errors.New("browser session not acquired")
+	// ErrNoBrowsersAvailable is returned by Acquire when every free set is empty.
+	ErrNoBrowsersAvailable = errors.New("no browsers available")
+	// ErrTimeout is returned when the worker does not answer within the RPC wait.
+	ErrTimeout = errors.New("timed out waiting for worker response")
+	// ErrActionFailed is matched by every *ActionError.
+	ErrActionFailed = errors.New("action failed")
+	// ErrAssertionFailed is matched by every *AssertionError.
+	ErrAssertionFailed = errors.New("assertion failed")
+	// ErrInvalidResponse is returned when Redis or the worker sends something
+	// the SDK cannot interpret.
+	ErrInvalidResponse = errors.New("invalid response")
+	// ErrLeaseLost is returned when a browser lease expired (and may have been
+	// reclaimed) or is held by someone else.
+	ErrLeaseLost = errors.New("browser lease lost")
+	// ErrPoolClosed is returned by Pool.Borrow once the pool is closed.
+	ErrPoolClosed = errors.New("pool closed")
+	// ErrProfileNotFound is returned for a profile name that is not registered.
+	ErrProfileNotFound = errors.New("profile not found")
+	// ErrProfileExists is returned when creating or renaming onto a taken name.
+	ErrProfileExists = errors.New("profile already exists")
+	// ErrProfileLocked is returned by Acquire when another session holds the
+	// profile, and by Profiles.Delete for a profile in use.
+	ErrProfileLocked = errors.New("profile locked")
+	// ErrInvalidConfig is matched by every *ConfigError.
+	ErrInvalidConfig = errors.New("invalid configuration")
+)
 
 // BrowserError is the custom error type for the SDK
 type BrowserError struct {
 	Message string
+	Kind    error // One of the sentinel errors above, if any
+	Err     error // Underlying cause (Redis, JSON, context...), if any
 }
 
 func (e *BrowserError) Error() string {
 	return fmt.Sprintf("isoAutomate Error: %s", e.Message)
 }
 
+// Unwrap exposes both the sentinel kind and the underlying cause to
+// errors.Is and errors.As.
+func (e *BrowserError) Unwrap() []error {
+	var errs []error
+	if e.Kind != nil {
+		errs = append(errs, e.Kind)
+	}
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	return errs
+}
+
 // NewBrowserError helps create a new error
 func NewBrowserError(format string, a ...interface{}) error {
 	return &BrowserError{
 		Message: fmt.Sprintf(format, a...),
 	}
 }
+
+// newError creates a BrowserError of the given kind wrapping cause.
+// Either kind or cause may be nil.
+func newError(kind, cause error, format string, a ...interface{}) error {
+	return &BrowserError{
+		Message: fmt.Sprintf(format, a...),
+		Kind:    kind,
+		Err:     cause,
+	}
+}
+
+// ActionError is returned when the worker executed an action and reported
+// status "error".
+type ActionError struct {
+	Action   string
+	Message  string                 // Error text reported by the worker
+	Response map[string]interface{} // Full worker response
+}
+
+func (e *ActionError) Error() string {
+	return fmt.Sprintf("isoAutomate Error: Action '%s' failed: %s", e.Action, e.Message)
+}
+
+// Is reports whether target is ErrActionFailed.
+func (e *ActionError) Is(target error) bool {
+	return target == ErrActionFailed
+}
+
+// AssertionError is returned by the Assert* methods when the check fails on
+// the page. Transport failures are reported as other errors, never as this.
+type AssertionError struct {
+	Action         string
+	Message        string                 // Failure text reported by the worker
+	ScreenshotPath string                 // Local failure screenshot, empty if none was saved
+	Response       map[string]interface{} // Full worker response
+}
+
+func (e *AssertionError) Error() string {
+	return fmt.Sprintf("isoAutomate Error: Assertion Failed: %s", e.Message)
+}
+
+// Is reports whether target is ErrAssertionFailed.
+func (e *AssertionError) Is(target error) bool {
+	return target == ErrAssertionFailed
+}
+
+// ReleaseError is returned when releasing several sessions at once
+// (Client.ReleaseAll, Pool.Close, a ShutdownManager) failed for some of them.
+type ReleaseError struct {
+	Failures []ReleaseFailure
+}
+
+// ReleaseFailure is a session that could not be released.
+type ReleaseFailure struct {
+	Session Session // The session as it was before the release
+	Err     error
+}
+
+func (e *ReleaseError) Error() string {
+	parts := make([]string, len(e.Failures))
+	for i, f := range e.Failures {
+		parts[i] = fmt.Sprintf("'%s' on %s: %v", f.Session.BrowserID, f.Session.WorkerName, f.Err)
+	}
+	return fmt.Sprintf("isoAutomate Error: %d session(s) could not be released: %s", len(e.Failures), strings.Join(parts, "; "))
+}
+
+// Unwrap exposes every failure's error to errors.Is and errors.As.
+func (e *ReleaseError) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, f := range e.Failures {
+		errs[i] = f.Err
+	}
+	return errs
+}
+
+// ConfigError is returned by New, Config.Resolve and Config.Validate when the
+// configuration is unusable. It lists every problem found, not just the
+// first.
+type ConfigError struct {
+	Problems []string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("isoAutomate Error: Invalid configuration: %s", strings.Join(e.Problems, "; "))
+}
+
+// Is reports whether target is ErrInvalidConfig.
+func (e *ConfigError) Is(target error) bool {
+	return target == ErrInvalidConfig
+}
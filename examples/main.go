@@ -23,25 +23,29 @@ func main() {
 
 	fmt.Println("Connected to Redis! Acquiring browser...")
 
+	// Release the browser on Ctrl+C or SIGTERM too, when deferred calls do not run
+	shutdown := client.HandleShutdown(isoautomate.ShutdownOptions{})
+	defer shutdown.Stop()
+
 	// 3. Acquire (Chrome, No Video, No Profile, No Record)
-	// Passing 'nil' for profile means standard ephemeral session
-	_, err = client.Acquire("chrome", false, nil, false)
+	// Leaving Profile unset means standard ephemeral session
+	browser, err := client.Acquire(isoautomate.AcquireOptions{BrowserType: "chrome"})
 	if err != nil {
 		log.Fatalf("Failed to acquire: %v", err)
 	}
 	// Ensure we release at the end
-	defer client.Release()
+	defer browser.Release()
 
 	// 4. Run Actions
 	fmt.Println("Browser acquired! Navigating...")
 
-	_, _ = client.OpenURL("https://google.com")
+	_, _ = browser.OpenURL("https://google.com")
 
-	titleRes, _ := client.GetTitle()
-	fmt.Printf("Page Title: %v\n", titleRes["value"])
+	titleRes, _ := browser.GetTitle()
+	fmt.Printf("Page Title: %s\n", titleRes.Value)
 
 	fmt.Println("Taking screenshot...")
-	pathRes, _ := client.Screenshot("example.png", "")
+	pathRes, _ := browser.Screenshot("example.png", "")
 	fmt.Printf("Screenshot saved to: %v\n", pathRes["path"])
 
 	time.Sleep(2 * time.Second)
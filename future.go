@@ -0,0 +1,122 @@
+package isoautomate
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Future is the pending result of a command sent with SendAsync.
+//
+// Ordering: tasks are pushed onto the worker's queue in the order SendAsync
+// returns, and the worker runs its queue first-in first-out, so actions take
+// effect on the page in that order. Calls made concurrently from several
+// goroutines are ordered only by when their push reaches Redis. Each result
+// is delivered to its own result key, so futures can be waited on in any
+// order and never receive another task's response.
+type Future struct {
+	TaskID string
+	Action string
+
+	done   chan struct{}
+	cancel context.CancelFunc
+	raw    []byte
+	err    error
+}
+
+// SendAsync enqueues a command and returns without waiting for the worker.
+// It returns an error only if the task could not be queued. ctx governs the
+// whole task: cancelling it has the same effect as Future.Cancel.
+func (s *BrowserSession) SendAsync(ctx context.Context, action string, args map[string]interface{}) (*Future, error) {
+	return s.SendAsyncWithTimeout(ctx, action, args, DefaultRPCWait)
+}
+
+// SendAsyncWithTimeout is SendAsync with a custom wait for the worker response.
+func (s *BrowserSession) SendAsyncWithTimeout(ctx context.Context, action string, args map[string]interface{}, timeout time.Duration) (*Future, error) {
+	return s.sendAsync(ctx, action, args, timeout)
+}
+
+func (s *BrowserSession) sendAsync(ctx context.Context, action string, args map[string]interface{}, timeout time.Duration) (*Future, error) {
+	task, err := s.enqueue(ctx, action, args)
+	if err != nil {
+		return nil, err
+	}
+
+	taskCtx, cancel := context.WithCancel(ctx)
+	f := &Future{
+		TaskID: task.ID,
+		Action: action,
+		done:   make(chan struct{}),
+		cancel: cancel,
+	}
+
+	go func() {
+		defer close(f.done)
+		defer cancel()
+		f.raw, f.err = s.client.collect(taskCtx, task, timeout)
+		if f.err != nil && task.initTask {
+			// Re-send the init flags with the next command
+			s.mu.Lock()
+			s.InitSent = false
+			s.mu.Unlock()
+		}
+	}()
+
+	return f, nil
+}
+
+// Done returns a channel that is closed once the result (or error) is available.
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Cancel stops waiting for the result and tells the worker to abandon the
+// task. It has no effect once the future is done.
+func (f *Future) Cancel() {
+	f.cancel()
+}
+
+// Wait blocks until the worker answers and returns the decoded response,
+// with an *ActionError for status "error" responses.
+// If ctx ends first, Wait returns ctx's error but the task keeps running;
+// call Cancel to abandon it.
+func (f *Future) Wait(ctx context.Context) (map[string]interface{}, error) {
+	raw, err := f.waitRaw(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return decodeMap(f.Action, raw)
+}
+
+func (f *Future) waitRaw(ctx context.Context) ([]byte, error) {
+	select {
+	case <-f.done:
+		return f.raw, f.err
+	case <-ctx.Done():
+		return nil, newError(nil, ctx.Err(), "Stopped waiting for '%s': %v", f.Action, ctx.Err())
+	}
+}
+
+// WaitTyped is Future.Wait decoding into T, with the same semantics as SendTyped.
+func WaitTyped[T any](ctx context.Context, f *Future) (T, error) {
+	raw, err := f.waitRaw(ctx)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return decodeResponse[T](f.Action, raw)
+}
+
+// WaitAll waits for every future and returns the failures (including
+// worker-reported action errors) joined together. Use Wait or WaitTyped
+// afterwards to read individual results; they return immediately once a
+// future is done.
+func WaitAll(ctx context.Context, futures ...*Future) error {
+	var errs []error
+	for _, f := range futures {
+		if _, err := f.Wait(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
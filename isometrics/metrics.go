@@ -0,0 +1,200 @@
+// Package isometrics exports an isoAutomate client's task and session
+// metrics, and the fleet's browser availability, to Prometheus. It is kept
+// out of the isoautomate package so programs that do not use Prometheus do
+// not link it:
+//
+//	metrics := isometrics.New(isometrics.Options{})
+//	prometheus.MustRegister(metrics, isometrics.NewFleetCollector(client))
+//	client.SetMetrics(metrics) // Or isoautomate.Config{Metrics: metrics}
+package isometrics
+
+import (
+	"context"
+	"time"
+
+	isoautomate "github.com/isoAutomate/isoautomate-go"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultLatencyBuckets spans 10ms to about 80s, past
+// isoautomate.DefaultRPCWait.
+var DefaultLatencyBuckets = prometheus.ExponentialBuckets(0.01, 2, 14)
+
+// Options customizes New. Zero fields take their default.
+type Options struct {
+	Buckets []float64 // Task latency buckets in seconds (default DefaultLatencyBuckets)
+}
+
+// Metrics is a Prometheus collector for a client's tasks and sessions:
+//
+//	isoautomate_task_duration_seconds{action,outcome}  histogram of task latency
+//	isoautomate_task_timeouts_total{action}            tasks with no answer in time
+//	isoautomate_assertion_failures_total{action}       assertions the page failed
+//	isoautomate_worker_errors_total{action,worker}     actions the worker failed
+//	isoautomate_transport_retries_total{operation}     retried Redis calls ("enqueue", "await")
+//	isoautomate_sessions_held                          sessions acquired or attached, not released
+//
+// The outcome label takes the isoautomate.Outcome values, as logged in the
+// "outcome" attribute. Register it with a prometheus.Registerer (wrap it
+// with prometheus.WrapRegistererWith for constant labels) and hand it to
+// clients through Config.Metrics or Client.SetMetrics; clients sharing one
+// Metrics add up.
+type Metrics struct {
+	latency           *prometheus.HistogramVec
+	timeouts          *prometheus.CounterVec
+	assertionFailures *prometheus.CounterVec
+	workerErrors      *prometheus.CounterVec
+	retries           *prometheus.CounterVec
+	sessions          prometheus.Gauge
+}
+
+var (
+	_ prometheus.Collector        = (*Metrics)(nil)
+	_ isoautomate.MetricsRecorder = (*Metrics)(nil)
+)
+
+// New creates an unregistered Metrics.
+func New(opts Options) *Metrics {
+	buckets := opts.Buckets
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	counter := func(name, help string, labels ...string) *prometheus.CounterVec {
+		return prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "isoautomate",
+			Name:      name,
+			Help:      help,
+		}, labels)
+	}
+
+	return &Metrics{
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "isoautomate",
+			Name:      "task_duration_seconds",
+			Help:      "Time from sending a task to its worker until its result is in.",
+			Buckets:   buckets,
+		}, []string{"action", "outcome"}),
+		timeouts:          counter("task_timeouts_total", "Tasks whose worker did not answer in time.", "action"),
+		assertionFailures: counter("assertion_failures_total", "Assertions that failed on the page.", "action"),
+		workerErrors:      counter("worker_errors_total", "Actions the worker reported as failed.", "action", "worker"),
+		retries:           counter("transport_retries_total", "Transport calls retried after a transient failure.", "operation"),
+		sessions: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "isoautomate",
+			Name:      "sessions_held",
+			Help:      "Browser sessions acquired or attached and not released yet.",
+		}),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.latency.Describe(ch)
+	m.timeouts.Describe(ch)
+	m.assertionFailures.Describe(ch)
+	m.workerErrors.Describe(ch)
+	m.retries.Describe(ch)
+	m.sessions.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.latency.Collect(ch)
+	m.timeouts.Collect(ch)
+	m.assertionFailures.Collect(ch)
+	m.workerErrors.Collect(ch)
+	m.retries.Collect(ch)
+	m.sessions.Collect(ch)
+}
+
+// The isoautomate.MetricsRecorder methods below are no-ops on a nil
+// *Metrics.
+
+// ObserveTask records a finished task with its outcome.
+func (m *Metrics) ObserveTask(action, worker, outcome string, latency time.Duration) {
+	if m == nil {
+		return
+	}
+	m.latency.WithLabelValues(action, outcome).Observe(latency.Seconds())
+	switch outcome {
+	case isoautomate.OutcomeTimeout:
+		m.timeouts.WithLabelValues(action).Inc()
+	case isoautomate.OutcomeAssertionFailed:
+		m.assertionFailures.WithLabelValues(action).Inc()
+	case isoautomate.OutcomeActionError:
+		m.workerErrors.WithLabelValues(action, worker).Inc()
+	}
+}
+
+// TransportRetried counts a retry of a transport operation.
+func (m *Metrics) TransportRetried(operation string) {
+	if m != nil {
+		m.retries.WithLabelValues(operation).Inc()
+	}
+}
+
+// SessionsHeld moves the sessions gauge by delta.
+func (m *Metrics) SessionsHeld(delta int) {
+	if m != nil {
+		m.sessions.Add(float64(delta))
+	}
+}
+
+// DefaultFleetScrapeTimeout bounds the Redis reads of one FleetCollector
+// scrape.
+const DefaultFleetScrapeTimeout = 5 * time.Second
+
+// FleetCollector is a Prometheus collector for the fleet's availability:
+//
+//	isoautomate_fleet_browsers{worker,browser_type,state}  browsers in the worker's "free" or "busy" set
+//
+// It reads the ISOAUTOMATE:<worker>:<type>:free|busy sets through the
+// client's transport on every scrape, so one process scraping the fleet is
+// enough. A failed read fails the scrape.
+type FleetCollector struct {
+	transport isoautomate.Transport
+	timeout   time.Duration
+	browsers  *prometheus.Desc
+}
+
+var _ prometheus.Collector = (*FleetCollector)(nil)
+
+// NewFleetCollector creates an unregistered FleetCollector for the fleet c
+// is connected to. Scrapes give up after DefaultFleetScrapeTimeout.
+//
+// Only the browser types in the ISOAUTOMATE:browser_types set are counted:
+// every type an Acquire has asked for, plus any a worker adds there itself.
+// A type that no client has acquired yet and that its workers do not
+// announce is missing from the metric, even while its free set is full.
+func NewFleetCollector(c *isoautomate.Client) *FleetCollector {
+	return &FleetCollector{
+		transport: c.Transport(),
+		timeout:   DefaultFleetScrapeTimeout,
+		browsers: prometheus.NewDesc("isoautomate_fleet_browsers",
+			"Browsers in a worker's free or busy set.",
+			[]string{"worker", "browser_type", "state"}, nil),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (f *FleetCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- f.browsers
+}
+
+// Collect implements prometheus.Collector.
+func (f *FleetCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), f.timeout)
+	defer cancel()
+
+	counts, err := f.transport.BrowserCounts(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(f.browsers, &isoautomate.BrowserError{
+			Message: "Failed to read the fleet's browser sets: " + err.Error(),
+			Err:     err,
+		})
+		return
+	}
+	for _, bc := range counts {
+		ch <- prometheus.MustNewConstMetric(f.browsers, prometheus.GaugeValue, float64(bc.Free), bc.Worker, bc.BrowserType, "free")
+		ch <- prometheus.MustNewConstMetric(f.browsers, prometheus.GaugeValue, float64(bc.Busy), bc.Worker, bc.BrowserType, "busy")
+	}
+}
@@ -0,0 +1,364 @@
+// Package isotest runs a fake isoFleet in process so code built on the SDK
+// can be tested without real browsers or a Redis server.
+//
+// A Fleet embeds a pure-Go Redis stand-in (miniredis), registers its workers
+// in ISOAUTOMATE:workers and their browser types in ISOAUTOMATE:browser_types,
+// fills the per-worker :free sets read by Acquire's Lua script and consumes
+// ISOAUTOMATE:<worker>:tasks, answering each task
+// from a scriptable handler table:
+//
+//	fleet := isotest.StartT(t, isotest.Worker{Name: "w1", Browsers: map[string]int{"chrome": 2}})
+//	fleet.Returns("get_title", map[string]interface{}{"value": "Example Domain"})
+//	fleet.Fails("click", "element not found: #missing")
+//
+//	client, err := isoautomate.New(fleet.Config())
+//
+// StartWith serves another namespace or the hash-tagged Cluster keyspace.
+package isotest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/isoAutomate/isoautomate-go"
+)
+
+// Worker describes one fake worker and how many browsers of each type it offers.
+type Worker struct {
+	Name     string
+	Browsers map[string]int    // Browser type -> number of browsers
+	Labels   map[string]string // Written to ISOAUTOMATE:<worker>:labels for AcquireOptions.Labels
+}
+
+// HandlerFunc computes the worker response for a task. A response without
+// "status" is sent with status "ok".
+type HandlerFunc func(task isoautomate.TaskPayload) map[string]interface{}
+
+// Options sets the keyspace the fleet serves. The zero value matches a
+// client with the default Config.
+type Options struct {
+	// Namespace prefixes the fleet's keys (default
+	// isoautomate.DefaultNamespace); see isoautomate.Config.Namespace.
+	Namespace string
+
+	// Cluster serves the hash-tagged keyspace of a Redis Cluster, and makes
+	// Config connect through a Cluster client (the embedded Redis answers as
+	// a one-node cluster).
+	Cluster bool
+}
+
+// Fleet is a running fake fleet.
+type Fleet struct {
+	// Redis is the embedded Redis stand-in; tests may inspect or modify keys directly.
+	Redis *miniredis.Miniredis
+
+	opts   Options
+	prefix string // isoautomate.KeyPrefix of opts
+	rdb    *redis.Client
+	stop   chan struct{}
+	wg     sync.WaitGroup
+
+	mu       sync.Mutex
+	handlers map[string]HandlerFunc
+	tasks    []isoautomate.TaskPayload
+	workers  []string
+	types    map[string][]string // Worker -> browser types
+	seen     map[string]bool     // Idempotency keys already handled
+}
+
+// Start launches the embedded Redis and the given workers.
+func Start(workers ...Worker) (*Fleet, error) {
+	return StartWith(Options{}, workers...)
+}
+
+// StartWith is Start for a fleet in another keyspace.
+func StartWith(opts Options, workers ...Worker) (*Fleet, error) {
+	if opts.Namespace == "" {
+		opts.Namespace = isoautomate.DefaultNamespace
+	}
+	mr, err := miniredis.Run()
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Fleet{
+		Redis:    mr,
+		opts:     opts,
+		prefix:   isoautomate.KeyPrefix(opts.Namespace, opts.Cluster),
+		rdb:      redis.NewClient(&redis.Options{Addr: mr.Addr()}),
+		stop:     make(chan struct{}),
+		handlers: make(map[string]HandlerFunc),
+		types:    make(map[string][]string),
+		seen:     make(map[string]bool),
+	}
+	f.installDefaults()
+
+	for _, w := range workers {
+		if err := f.AddWorker(w); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// StartT is Start for tests: it fails tb on error and closes the fleet
+// when the test ends.
+func StartT(tb testing.TB, workers ...Worker) *Fleet {
+	tb.Helper()
+	return StartTWith(tb, Options{}, workers...)
+}
+
+// StartTWith is StartT for a fleet in another keyspace.
+func StartTWith(tb testing.TB, opts Options, workers ...Worker) *Fleet {
+	tb.Helper()
+
+	f, err := StartWith(opts, workers...)
+	if err != nil {
+		tb.Fatalf("isotest: starting fleet: %v", err)
+	}
+	tb.Cleanup(f.Close)
+	return f
+}
+
+// Close stops the workers and the embedded Redis.
+func (f *Fleet) Close() {
+	close(f.stop)
+
+	// Wake the workers' blocking BLPOPs so they notice the stop.
+	f.mu.Lock()
+	workers := append([]string(nil), f.workers...)
+	f.mu.Unlock()
+	for _, w := range workers {
+		_ = f.rdb.LPush(context.Background(), f.queueKey(w), "").Err()
+	}
+
+	f.wg.Wait()
+	_ = f.rdb.Close()
+	f.Redis.Close()
+}
+
+// Config returns a Config pointing at the embedded Redis in the fleet's
+// keyspace, ready for isoautomate.New.
+func (f *Fleet) Config() isoautomate.Config {
+	cfg := isoautomate.Config{Namespace: f.opts.Namespace}
+	if f.opts.Cluster {
+		cfg.RedisClusterAddrs = []string{f.Redis.Addr()}
+	} else {
+		cfg.RedisURL = "redis://" + f.Redis.Addr()
+	}
+	return cfg
+}
+
+// AddWorker registers a worker, creates its free browsers (named
+// "<worker>-<type>-<n>") and starts consuming its task queue.
+func (f *Fleet) AddWorker(w Worker) error {
+	if w.Name == "" {
+		return errors.New("isotest: worker needs a name")
+	}
+
+	if _, err := f.Redis.SAdd(f.prefix+"workers", w.Name); err != nil {
+		return err
+	}
+	for k, v := range w.Labels {
+		f.Redis.HSet(f.prefix+w.Name+":labels", k, v)
+	}
+	f.mu.Lock()
+	f.workers = append(f.workers, w.Name)
+	f.mu.Unlock()
+
+	for browserType, n := range w.Browsers {
+		if _, err := f.Redis.SAdd(f.prefix+"browser_types", browserType); err != nil {
+			return err
+		}
+		f.mu.Lock()
+		f.types[w.Name] = append(f.types[w.Name], browserType)
+		f.mu.Unlock()
+
+		for i := 1; i <= n; i++ {
+			bid := fmt.Sprintf("%s-%s-%d", w.Name, browserType, i)
+			if _, err := f.Redis.SAdd(f.setKey(w.Name, browserType, "free"), bid); err != nil {
+				return err
+			}
+		}
+	}
+
+	f.wg.Add(1)
+	go f.serve(w.Name)
+	return nil
+}
+
+// --- Handler Table ---
+
+// Handle installs a handler for an action, replacing any previous one.
+func (f *Fleet) Handle(action string, h HandlerFunc) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.handlers[action] = h
+}
+
+// Returns makes action succeed with the given response fields,
+// e.g. Returns("get_title", map[string]interface{}{"value": "X"}).
+func (f *Fleet) Returns(action string, response map[string]interface{}) {
+	f.Handle(action, func(isoautomate.TaskPayload) map[string]interface{} {
+		return copyMap(response)
+	})
+}
+
+// Fails makes action answer with status "error" and the given message.
+func (f *Fleet) Fails(action, message string) {
+	f.Returns(action, map[string]interface{}{"status": "error", "error": message})
+}
+
+// Tasks returns every task the fleet has received, in arrival order.
+func (f *Fleet) Tasks() []isoautomate.TaskPayload {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]isoautomate.TaskPayload(nil), f.tasks...)
+}
+
+// TasksFor returns the received tasks for one action.
+func (f *Fleet) TasksFor(action string) []isoautomate.TaskPayload {
+	var out []isoautomate.TaskPayload
+	for _, t := range f.Tasks() {
+		if t.Action == action {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// --- Fleet State ---
+
+// Free returns the sorted free browser IDs of a worker.
+func (f *Fleet) Free(worker, browserType string) []string {
+	return f.members(f.setKey(worker, browserType, "free"))
+}
+
+// Busy returns the sorted busy browser IDs of a worker.
+func (f *Fleet) Busy(worker, browserType string) []string {
+	return f.members(f.setKey(worker, browserType, "busy"))
+}
+
+func (f *Fleet) members(key string) []string {
+	if !f.Redis.Exists(key) {
+		return nil
+	}
+	ids, _ := f.Redis.Members(key)
+	sort.Strings(ids)
+	return ids
+}
+
+func (f *Fleet) queueKey(worker string) string {
+	return fmt.Sprintf("%s%s:tasks", f.prefix, worker)
+}
+
+func (f *Fleet) setKey(worker, browserType, state string) string {
+	return fmt.Sprintf("%s%s:%s:%s", f.prefix, worker, browserType, state)
+}
+
+// --- Worker Loop ---
+
+// installDefaults answers the lifecycle actions the SDK sends on its own.
+func (f *Fleet) installDefaults() {
+	f.handlers["release_browser"] = func(task isoautomate.TaskPayload) map[string]interface{} {
+		f.mu.Lock()
+		types := append([]string(nil), f.types[task.WorkerName]...)
+		f.mu.Unlock()
+
+		for _, browserType := range types {
+			busy := f.setKey(task.WorkerName, browserType, "busy")
+			if moved, _ := f.Redis.SIsMember(busy, task.BrowserID); moved {
+				_, _ = f.Redis.SRem(busy, task.BrowserID)
+				_, _ = f.Redis.SAdd(f.setKey(task.WorkerName, browserType, "free"), task.BrowserID)
+			}
+		}
+		return map[string]interface{}{"status": "ok", "browser_id": task.BrowserID}
+	}
+	f.handlers["reset_browser"] = func(task isoautomate.TaskPayload) map[string]interface{} {
+		return map[string]interface{}{"browser_id": task.BrowserID}
+	}
+	f.handlers["stop_video"] = func(task isoautomate.TaskPayload) map[string]interface{} {
+		return map[string]interface{}{"video_url": "https://fleet.test/video/" + task.BrowserID + ".mp4"}
+	}
+	f.handlers["stop_record"] = func(task isoautomate.TaskPayload) map[string]interface{} {
+		return map[string]interface{}{"record_url": "https://fleet.test/record/" + task.BrowserID + ".json"}
+	}
+}
+
+func (f *Fleet) serve(worker string) {
+	defer f.wg.Done()
+
+	queue := f.queueKey(worker)
+	for {
+		vals, err := f.rdb.BLPop(context.Background(), time.Second, queue).Result()
+
+		select {
+		case <-f.stop:
+			return
+		default:
+		}
+		if err != nil || len(vals) < 2 {
+			continue
+		}
+
+		var task isoautomate.TaskPayload
+		if err := json.Unmarshal([]byte(vals[1]), &task); err != nil {
+			continue
+		}
+		f.dispatch(task)
+	}
+}
+
+func (f *Fleet) dispatch(task isoautomate.TaskPayload) {
+	f.mu.Lock()
+	// A retried push that had already landed: a real worker drops it.
+	if task.IdempotencyKey != "" {
+		if f.seen[task.IdempotencyKey] {
+			f.mu.Unlock()
+			return
+		}
+		f.seen[task.IdempotencyKey] = true
+	}
+	f.tasks = append(f.tasks, task)
+	h := f.handlers[task.Action]
+	f.mu.Unlock()
+
+	// The SDK gave up on this task: a real worker skips it.
+	if f.Redis.Exists(f.prefix + "cancel:" + task.TaskID) {
+		return
+	}
+
+	var resp map[string]interface{}
+	if h != nil {
+		resp = h(task)
+	}
+	if resp == nil {
+		resp = map[string]interface{}{}
+	}
+	if _, ok := resp["status"]; !ok {
+		resp["status"] = "ok"
+	}
+
+	data, _ := json.Marshal(resp)
+	_ = f.rdb.RPush(context.Background(), task.ResultKey, data).Err()
+}
+
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,90 @@
+package isoautomate
+
+import "strings"
+
+// keyspace names the Redis keys of one fleet. Every key starts with the
+// fleet's namespace, "ISOAUTOMATE:" by default (see Config.Namespace).
+//
+// On Redis Cluster the namespace is wrapped in a hash tag ("{ISOAUTOMATE}:"),
+// which puts all of the fleet's keys in one hash slot: the Lua scripts read
+// and move the browser sets of every worker, so their keys must live on one
+// node. Workers attached to a Cluster use the same hash-tagged prefix.
+type keyspace struct {
+	prefix string
+}
+
+// KeyPrefix is the prefix of every key of the fleet in namespace, as workers
+// and tools outside the SDK must spell it: "<namespace>:", or
+// "{<namespace>}:" on Redis Cluster.
+func KeyPrefix(namespace string, cluster bool) string {
+	return newKeyspace(namespace, cluster).prefix
+}
+
+func newKeyspace(namespace string, cluster bool) keyspace {
+	if cluster {
+		return keyspace{prefix: "{" + namespace + "}:"}
+	}
+	return keyspace{prefix: namespace + ":"}
+}
+
+// validNamespace reports whether namespace can prefix keys: it must not be
+// empty or contain the characters used by hash tags and key separators.
+func validNamespace(namespace string) bool {
+	return namespace != "" && !strings.ContainsAny(namespace, "{}: \t\n")
+}
+
+// workers is the set of registered worker names.
+func (k keyspace) workers() string { return k.prefix + "workers" }
+
+// browserTypes is the set of browser types in use: every acquire adds its
+// type, and workers may add theirs (see BrowserCounts).
+func (k keyspace) browserTypes() string { return k.prefix + "browser_types" }
+
+// tasks is a worker's task queue.
+func (k keyspace) tasks(worker string) string { return k.prefix + worker + ":tasks" }
+
+// browsers is a worker's "free" or "busy" set for a browser type.
+func (k keyspace) browsers(worker, browserType, state string) string {
+	return k.prefix + worker + ":" + browserType + ":" + state
+}
+
+// result is the list a worker pushes a task's response onto.
+func (k keyspace) result(taskID string) string { return k.prefix + "result:" + taskID }
+
+// cancel marks an abandoned task for its worker.
+func (k keyspace) cancel(taskID string) string { return k.prefix + "cancel:" + taskID }
+
+// Lease bookkeeping (see luaNowMS)
+func (k keyspace) leases() string      { return k.prefix + "leases" }
+func (k keyspace) leaseTokens() string { return k.prefix + "lease_tokens" }
+
+// profiles maps profile names to their JSON metadata, and IDs of deleted
+// profiles are added to profiles:deleted for workers to purge.
+func (k keyspace) profiles() string        { return k.prefix + "profiles" }
+func (k keyspace) deletedProfiles() string { return k.prefix + "profiles:deleted" }
+
+// profileWorkers maps each profile ID to the worker that last ran it (see
+// AcquireOptions.Affinity).
+func (k keyspace) profileWorkers() string { return k.prefix + "profile_workers" }
+
+// profileLock holds the lock of one profile. Locks are keyed by profile ID,
+// so renaming a profile does not release its lock.
+func (k keyspace) profileLock(profileID string) string {
+	return k.prefix + "profile_lock:" + profileID
+}
+
+// Acquire wait queues (see luaQueueAhead) and their release notifications
+func (k keyspace) queue(browserType string) string {
+	return k.prefix + "queue:" + browserType
+}
+
+func (k keyspace) queueStats(browserType string) string {
+	return k.prefix + "queue:" + browserType + ":stats"
+}
+
+func (k keyspace) released(browserType string) string {
+	return k.prefix + "released:" + browserType
+}
+
+// roundRobin counts acquires per browser type for StrategyRoundRobin.
+func (k keyspace) roundRobin(browserType string) string { return k.prefix + "rr:" + browserType }
@@ -0,0 +1,254 @@
+package isoautomate
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultLeaseTTL is how long a browser stays reserved without a renewal.
+// The client renews the lease every third of the TTL while a session is
+// active, so a crashed process gives its browsers back after at most one TTL
+// (once a reaper runs).
+const DefaultLeaseTTL = 60 * time.Second
+
+// MinLeaseTTL is the shortest lease Config accepts: renewals every third of
+// it must still have time to reach Redis.
+const MinLeaseTTL = 3 * time.Second
+
+// Lease is a time-limited reservation of a busy browser.
+type Lease struct {
+	Worker      string    `json:"worker"`
+	BrowserType string    `json:"browser_type"`
+	BrowserID   string    `json:"browser_id"`
+	Token       string    `json:"token"` // Identifies the holder; only it can renew or drop the lease
+	Expires     time.Time `json:"expires"`
+}
+
+// member is the lease's identity in the leases sorted set.
+func (l Lease) member() string {
+	return l.Worker + "|" + l.BrowserType + "|" + l.BrowserID
+}
+
+func parseLeaseMember(member string) (Lease, bool) {
+	parts := strings.SplitN(member, "|", 3)
+	if len(parts) != 3 {
+		return Lease{}, false
+	}
+	return Lease{Worker: parts[0], BrowserType: parts[1], BrowserID: parts[2]}, true
+}
+
+// keepalive renews the session lease in the background until stopped.
+type keepalive struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu    sync.Mutex
+	lease Lease
+	err   error // Set if the lease was lost
+}
+
+// startKeepalive begins renewing lease every ttl/3.
+func (c *Client) startKeepalive(lease Lease, ttl time.Duration) *keepalive {
+	ctx, cancel := context.WithCancel(context.Background())
+	k := &keepalive{cancel: cancel, done: make(chan struct{}), lease: lease}
+
+	go func() {
+		defer close(k.done)
+
+		err := renewUntilLost(ctx, ttl, func(ctx context.Context) error {
+			expires, err := c.transport.RenewLease(ctx, lease, ttl)
+			if err == nil {
+				k.mu.Lock()
+				k.lease.Expires = expires
+				k.mu.Unlock()
+			}
+			return err
+		})
+		if err != nil {
+			k.mu.Lock()
+			k.err = err
+			k.mu.Unlock()
+			c.logger.LogAttrs(context.Background(), slog.LevelWarn, "lease lost",
+				slog.String("worker", lease.Worker),
+				slog.String("browser_id", lease.BrowserID),
+				slog.String("browser_type", lease.BrowserType))
+		}
+	}()
+	return k
+}
+
+// renewUntilLost calls renew every ttl/3 (never more often than MinLeaseTTL/3)
+// until ctx is done, or until renew fails with ErrLeaseLost, which it
+// returns. Transient errors are retried on the next tick; the TTL leaves
+// room for two misses.
+func renewUntilLost(ctx context.Context, ttl time.Duration, renew func(context.Context) error) error {
+	ticker := time.NewTicker(max(ttl, MinLeaseTTL) / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		if err := renew(ctx); errors.Is(err, ErrLeaseLost) {
+			return err
+		}
+	}
+}
+
+// stop ends the renewals and waits for the goroutine to exit.
+func (k *keepalive) stop() {
+	k.cancel()
+	<-k.done
+}
+
+// current returns the lease with its latest expiry, and the error that
+// ended the renewals, if any.
+func (k *keepalive) current() (Lease, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.lease, k.err
+}
+
+// Lease returns the lease held by the session. The error is ErrNotAcquired
+// without an active session, or ErrLeaseLost if the lease expired and was
+// reclaimed while the session was still in use.
+func (s *BrowserSession) Lease() (Lease, error) {
+	s.mu.Lock()
+	k := s.keepalive
+	active := s.Session != nil
+	s.mu.Unlock()
+
+	if !active || k == nil {
+		return Lease{}, newError(ErrNotAcquired, nil, "No active session")
+	}
+	lease, err := k.current()
+	if err != nil {
+		return lease, newError(ErrLeaseLost, err, "Lease on browser '%s' was lost", lease.BrowserID)
+	}
+	return lease, nil
+}
+
+// --- Reaper ---
+
+// ReapExpiredLeases returns every browser whose lease has expired (its
+// holder crashed or stopped renewing) to its worker's free set, and returns
+// the reclaimed leases.
+//
+// Each browser is first sent a "reset_browser" task, and only freed once
+// that task is on its worker's queue. Worker queues are first-in first-out,
+// so the reset runs before any command from the browser's next holder. Until
+// then the reaper holds the lease itself: if it cannot queue the reset (or
+// crashes), the browser stays busy and is reaped again once that lease
+// expires. ctx bounds queueing the resets; a reset once queued is not
+// cancelled, and its result is collected in the background.
+func (c *Client) ReapExpiredLeases(ctx context.Context) ([]Lease, error) {
+	token := newHexID()
+	leases, err := c.transport.ReapExpiredLeases(ctx, token, c.leaseTTL)
+	if err != nil {
+		return nil, newError(nil, err, "Failed to reap expired leases: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	reclaimed := make([]Lease, 0, len(leases))
+	for _, lease := range leases {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if c.resetBrowser(ctx, lease, token) {
+				mu.Lock()
+				reclaimed = append(reclaimed, lease)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	return reclaimed, nil
+}
+
+// RunReaper calls ReapExpiredLeases every interval until ctx is done. It is
+// meant for a long-running service (or a sidecar) so leaked browsers come
+// back even if the process that leaked them never restarts. onReap, if not
+// nil, is called with each non-empty batch of reclaimed leases.
+func (c *Client) RunReaper(ctx context.Context, interval time.Duration, onReap func([]Lease)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		leases, err := c.ReapExpiredLeases(ctx)
+		if err == nil && len(leases) > 0 && onReap != nil {
+			onReap(leases)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// resetBrowser asks the worker to wipe a browser the reaper took over with
+// token, frees the browser once the reset is queued and reports whether it
+// did. A queued reset is never cancelled, even if ctx ends: the browser's
+// next holder relies on it.
+func (c *Client) resetBrowser(ctx context.Context, lease Lease, token string) bool {
+	attrs := []slog.Attr{
+		slog.String("worker", lease.Worker),
+		slog.String("browser_id", lease.BrowserID),
+		slog.String("browser_type", lease.BrowserType),
+	}
+	taskID := newHexID()
+	payload := TaskPayload{
+		TaskID:         taskID,
+		IdempotencyKey: taskID,
+		BrowserID:      lease.BrowserID,
+		WorkerName:     lease.Worker,
+		Action:         "reset_browser",
+		Args:           map[string]interface{}{"reason": "lease_expired"},
+		ResultKey:      c.keys.result(taskID),
+	}
+	task := &pendingTask{
+		ID:        taskID,
+		ResultKey: payload.ResultKey,
+		Action:    payload.Action,
+		Worker:    lease.Worker,
+		BrowserID: lease.BrowserID,
+		started:   time.Now(),
+	}
+	spanCtx, span := c.tracer.Start(ctx, payload.Action,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(taskSpanAttrs(task)...))
+	task.span = span
+	if err := c.push(spanCtx, task, payload); err != nil {
+		c.finishTask(ctx, task, nil, err)
+		return false
+	}
+
+	held := lease
+	held.Token = token
+	freeErr := c.transport.ReleaseLease(ctx, held)
+	if freeErr != nil {
+		c.logger.LogAttrs(ctx, slog.LevelWarn, "reclaimed browser not freed",
+			append(attrs, slog.String("error", freeErr.Error()))...)
+	} else {
+		c.logger.LogAttrs(ctx, slog.LevelInfo, "reclaimed expired lease", attrs...)
+	}
+
+	// Drain the answer in the background so the result key does not linger.
+	// Without a cancellable ctx, collect never abandons the task.
+	go func() {
+		_, _ = c.collect(context.WithoutCancel(ctx), task, 30*time.Second)
+	}()
+	return freeErr == nil
+}
@@ -1,147 +1,326 @@
 package isoautomate
 
 import (
-	"encoding/json"
-	"fmt"
-	"os"
-	"path/filepath"
+	"context"
+	"errors"
+	"log/slog"
 	"time"
 
-	"github.com/google/uuid"
-	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// AcquireOptions describes the browser to acquire and where it may run.
+type AcquireOptions struct {
+	BrowserType string // e.g. "chrome" (required)
+	Video       bool   // Record a video of the session
+	Record      bool   // Record an RRWeb session replay
+
+	// Profile names a persistent profile (see Client.Profiles), created on
+	// first use. Empty means an ephemeral session.
+	Profile string
+
+	// Workers, if not empty, restricts the acquire to these workers
+	// (a single entry pins the session to one worker).
+	Workers []string
+	// ExcludeWorkers lists workers that must not be used.
+	ExcludeWorkers []string
+	// Labels must all match the worker's metadata, stored by the worker in
+	// the ISOAUTOMATE:<worker>:labels hash (e.g. {"region": "eu-west"}).
+	Labels map[string]string
+
+	// Strategy orders the workers that pass the selection (default
+	// Config.Strategy, or StrategyRandom).
+	Strategy Strategy
+
+	// Affinity names a profile whose last worker is tried first, so the
+	// profile's data stays local to it. It defaults to the session's own
+	// profile.
+	Affinity string
+
+	// Wait, if set, makes Acquire queue for a browser instead of failing at
+	// once when none is free.
+	Wait *WaitOptions
+
+	// ProfileWait, if set, makes Acquire wait while another session holds
+	// Profile instead of failing at once with ErrProfileLocked.
+	ProfileWait *ProfileWaitOptions
+}
+
 // Acquire reserves a browser session using atomic Lua scripting.
-func (c *Client) Acquire(browserType string, video bool, profile interface{}, record bool) (map[string]interface{}, error) {
+// Each call returns an independent session. While the client's default
+// session (whose methods the Client exposes directly) holds no browser,
+// Acquire fills and returns it; otherwise it returns a new session and the
+// default session is left alone.
+func (c *Client) Acquire(opts AcquireOptions) (*BrowserSession, error) {
+	return c.AcquireContext(context.Background(), opts)
+}
+
+// AcquireContext is like Acquire but honours ctx for the Redis calls and the
+// initialization command.
+func (c *Client) AcquireContext(ctx context.Context, opts AcquireOptions) (*BrowserSession, error) {
+	if opts.BrowserType == "" {
+		return nil, NewBrowserError("Acquire needs a browser type")
+	}
+
+	s := c.claimSession()
+	if err := c.acquire(ctx, opts, s); err != nil {
+		c.unclaimSession(s)
+		return nil, err
+	}
+	return s, nil
+}
+
+// acquire reserves a browser and initializes s with it. s must hold no
+// browser and be reachable by nobody else until acquire returns, except
+// through the Client if it is the default session.
+func (c *Client) acquire(ctx context.Context, opts AcquireOptions, s *BrowserSession) error {
+	start := time.Now()
+	sessionCtx, sessionSpan := c.tracer.Start(ctx, "isoautomate.session",
+		trace.WithAttributes(attrBrowserType.String(opts.BrowserType)))
+	acquireCtx, acquireSpan := c.tracer.Start(sessionCtx, "isoautomate.acquire")
+
+	if err := c.newSession(acquireCtx, opts, s, sessionSpan); err != nil {
+		failSpan(acquireSpan, err)
+		failSpan(sessionSpan, err)
+
+		attrs := []slog.Attr{slog.String("browser_type", opts.BrowserType)}
+		if opts.Profile != "" {
+			attrs = append(attrs, slog.String("profile", opts.Profile))
+		}
+		attrs = append(attrs, slog.Duration("latency", time.Since(start)), slog.String("error", err.Error()))
+		c.logger.LogAttrs(ctx, slog.LevelWarn, "acquire failed", attrs...)
+		return err
+	}
+	sess := s.current()
+	acquireSpan.SetAttributes(sessionSpanAttrs(sess)...)
+	acquireSpan.End()
+	sessionSpan.SetAttributes(sessionSpanAttrs(sess)...)
+	c.logger.LogAttrs(ctx, slog.LevelInfo, "browser acquired",
+		sessionAttrs(sess, slog.Duration("latency", time.Since(start)))...)
+	return nil
+}
+
+// newSession does the work of acquire. span becomes the session span.
+func (c *Client) newSession(ctx context.Context, opts AcquireOptions, s *BrowserSession, span trace.Span) error {
+	browserType, video, record := opts.BrowserType, opts.Video, opts.Record
+
 	// 1. Handle Profile Logic
-	var profileID string
-	if profile != nil {
-		if pStr, ok := profile.(string); ok {
-			profileID = pStr
-		} else if pBool, ok := profile.(bool); ok && pBool {
-			// Auto-generate or load default profile ID
-			cwd, _ := os.Getwd()
-			profileStore := filepath.Join(cwd, ".iso_profiles")
-			_ = os.MkdirAll(profileStore, 0755)
-
-			idFile := filepath.Join(profileStore, "default_profile.id")
-			if data, err := os.ReadFile(idFile); err == nil {
-				profileID = string(data)
-			} else {
-				profileID = fmt.Sprintf("user_%s", uuid.New().Hex()[:8])
-				_ = os.WriteFile(idFile, []byte(profileID), 0644)
-			}
+	var prof Profile
+	if opts.Profile != "" {
+		var err error
+		if prof, err = c.Profiles().resolve(ctx, opts.Profile, browserType); err != nil {
+			return err
 		}
 	}
+	profileID := prof.ID
 
-	c.InitSent = false
-
-	// 2. The Lua Script (Exact copy of Python logic)
-	luaScript := `
-	local workers = redis.call('SMEMBERS', KEYS[1])
-	for i = #workers, 2, -1 do
-		local j = math.random(i)
-		workers[i], workers[j] = workers[j], workers[i]
-	end
-	
-	for _, worker in ipairs(workers) do
-		local free_key = ARGV[1] .. worker .. ':' .. ARGV[2] .. ':free'
-		local bid = redis.call('SPOP', free_key)
-		if bid then
-			local busy_key = ARGV[1] .. worker .. ':' .. ARGV[2] .. ':busy'
-			redis.call('SADD', busy_key, bid)
-			return {worker, bid}
-		end
-	end
-	return nil
-	`
+	affinity := profileID
+	if opts.Affinity != "" && opts.Affinity != opts.Profile {
+		// Best effort: an unknown profile just means no preference
+		if other, err := c.transport.GetProfile(ctx, opts.Affinity); err == nil {
+			affinity = other.ID
+		}
+	}
+	strategy := opts.Strategy
+	if strategy == "" {
+		strategy = c.strategy
+	}
+	if !strategy.valid() {
+		return NewBrowserError("Unknown worker selection strategy '%s'", strategy)
+	}
 
-	// 3. Execute Lua Script
-	cmd := c.R.Eval(c.ctx, luaScript, []string{WorkersSet}, RedisPrefix, browserType)
-	result, err := cmd.Result()
-	if err != nil {
-		return nil, NewBrowserError("Redis Lua Error: %v", err)
+	// The session owns the profile until Release; take it before the
+	// browser so a wait for the profile does not hold a browser.
+	var lock *profileLockKeeper
+	if profileID != "" {
+		var err error
+		if lock, err = c.lockProfile(ctx, prof, opts.ProfileWait); err != nil {
+			return err
+		}
 	}
 
-	if result == nil {
-		return nil, NewBrowserError("No browsers available for type: '%s'. Check workers.", browserType)
+	// 2. Atomically reserve and lease a free browser (Lua script on Redis)
+	req := AcquireRequest{
+		BrowserType: browserType,
+		LeaseTTL:    c.leaseTTL,
+		ProfileID:   profileID,
+		Workers:     opts.Workers,
+		Exclude:     opts.ExcludeWorkers,
+		Labels:      opts.Labels,
+		Affinity:    affinity,
+		Strategy:    strategy,
+	}
+	var lease Lease
+	var err error
+	if opts.Wait != nil {
+		waitStart := time.Now()
+		lease, err = c.acquireQueued(ctx, req, *opts.Wait)
+		trace.SpanFromContext(ctx).SetAttributes(attrQueueWait.Float64(float64(time.Since(waitStart)) / float64(time.Millisecond)))
+	} else {
+		lease, err = c.reserve(ctx, req)
+	}
+	if err != nil {
+		if lock != nil {
+			lock.unlock(ctx)
+		}
+		return err
 	}
 
-	// 4. Parse Result ([worker_name, browser_id])
-	resSlice, ok := result.([]interface{})
-	if !ok || len(resSlice) < 2 {
-		return nil, NewBrowserError("Invalid Lua response format")
+	workerName, bid := lease.Worker, lease.BrowserID
+	if prof.ID != "" {
+		prof = c.Profiles().touch(ctx, prof, browserType)
 	}
-	workerName := resSlice[0].(string)
-	bid := resSlice[1].(string)
 
-	// 5. Initialize Session
-	c.Session = &Session{
+	// 3. Initialize Session and keep the lease alive while it is in use
+	s.start(&Session{
 		BrowserID:   bid,
 		WorkerName:  workerName,
 		BrowserType: browserType,
 		Video:       video,
 		Record:      record,
 		ProfileID:   profileID,
-	}
+		ProfileName: prof.Name,
+		CloneFrom:   prof.ClonedFrom,
+	}, false, c.startKeepalive(lease, c.leaseTTL), lock, span)
 
 	// If persistence/video/record is needed, we must ensure the worker is ready.
 	// In Python, you called get_title to force initialization.
 	if profileID != "" || video || record {
-		fmt.Printf("[SDK] Initializing persistent environment on %s...\n", workerName)
-		_, _ = c.Send("get_title", nil)
+		c.logger.LogAttrs(ctx, slog.LevelDebug, "initializing session", sessionAttrs(s.Session)...)
+		_, _ = s.SendContext(ctx, "get_title", nil)
 	}
 
-	return map[string]interface{}{
-		"status":     "ok",
-		"browser_id": bid,
-		"worker":     workerName,
-	}, nil
+	return nil
+}
+
+// reserve makes one atomic acquire attempt for req with a fresh lease token.
+func (c *Client) reserve(ctx context.Context, req AcquireRequest) (Lease, error) {
+	req.LeaseToken = newHexID()
+	lease, err := c.transport.AcquireBrowser(ctx, req)
+	if errors.Is(err, ErrNoBrowsersAvailable) {
+		if len(req.Workers) > 0 || len(req.Exclude) > 0 || len(req.Labels) > 0 {
+			return Lease{}, newError(ErrNoBrowsersAvailable, nil, "No browsers available for type: '%s' on the selected workers.", req.BrowserType)
+		}
+		return Lease{}, newError(ErrNoBrowsersAvailable, nil, "No browsers available for type: '%s'. Check workers.", req.BrowserType)
+	}
+	if errors.Is(err, ErrInvalidResponse) {
+		return Lease{}, newError(ErrInvalidResponse, nil, "Invalid Lua response format")
+	}
+	if err != nil {
+		return Lease{}, newError(nil, err, "Redis Lua Error: %v", err)
+	}
+	return lease, nil
 }
 
 // Release cleanly closes the session, stopping video/recordings if active.
-func (c *Client) Release() (map[string]interface{}, error) {
-	if c.Session == nil {
+func (s *BrowserSession) Release() (map[string]interface{}, error) {
+	return s.ReleaseContext(context.Background())
+}
+
+// ReleaseContext is like Release but honours ctx. The session is dropped
+// locally even if ctx is cancelled before the worker confirms the release.
+func (s *BrowserSession) ReleaseContext(ctx context.Context) (map[string]interface{}, error) {
+	sess := s.current()
+	if sess == nil {
 		return map[string]interface{}{"status": "error", "error": "not_acquired"}, nil
 	}
 
+	// The lease is renewed until the worker has taken the browser back.
+	// If the release fails, the lease simply runs out and a reaper reclaims
+	// the browser.
+	s.mu.Lock()
+	sessionSpan := s.span
+	s.mu.Unlock()
+	if sessionSpan != nil {
+		ctx = trace.ContextWithSpan(ctx, sessionSpan)
+	}
+	ctx, releaseSpan := s.client.tracer.Start(ctx, "isoautomate.release", trace.WithAttributes(sessionSpanAttrs(sess)...))
+
+	released := false
 	defer func() {
-		c.Session = nil
+		s.mu.Lock()
+		k, lock, span := s.keepalive, s.profileLock, s.span
+		s.keepalive, s.profileLock, s.span = nil, nil, nil
+		s.Session = nil
+		s.mu.Unlock()
+		s.client.untrack(s)
+
+		// The profile lock outlives a failed release until it expires, like
+		// the lease, since the worker may still be using the profile.
+		if lock != nil {
+			if released {
+				lock.unlock(ctx)
+			} else {
+				lock.stop()
+			}
+		}
+		if k != nil {
+			k.stop()
+			if released {
+				lease, _ := k.current()
+				dropCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+				_ = s.client.transport.DropLease(dropCtx, lease)
+				// The worker freed the browser; wake waiting acquires.
+				_ = s.client.transport.NotifyReleased(dropCtx, sess.BrowserType)
+				cancel()
+			}
+		}
+		if span != nil {
+			if !released {
+				span.SetStatus(codes.Error, "release failed")
+			}
+			span.End()
+		}
 	}()
 
+	log := s.client.logger
+	start := time.Now()
+
 	// 1. Stop Video if active
-	if c.Session.Video {
-		fmt.Println("[SDK] Stopping video...")
+	if sess.Video {
+		log.LogAttrs(ctx, slog.LevelDebug, "stopping video", sessionAttrs(sess)...)
 		// Use a longer timeout for video processing (120s)
-		res, err := c.SendWithTimeout("stop_video", nil, 120*time.Second)
+		res, err := s.SendWithTimeoutContext(ctx, "stop_video", nil, 120*time.Second)
 		if err == nil {
 			if url, ok := res["video_url"].(string); ok {
-				c.VideoURL = url
-				fmt.Printf("[SDK] Session Video: %s\n", c.VideoURL)
+				s.mu.Lock()
+				s.VideoURL = url
+				s.mu.Unlock()
+				log.LogAttrs(ctx, slog.LevelInfo, "session video", sessionAttrs(sess, slog.String("url", url))...)
 			}
 		}
 	}
 
 	// 2. Stop Record (RRWeb) if active
-	if c.Session.Record {
-		fmt.Println("[SDK] Finalizing session record (RRWeb)...")
-		res, err := c.SendWithTimeout("stop_record", nil, 60*time.Second)
+	if sess.Record {
+		log.LogAttrs(ctx, slog.LevelDebug, "finalizing session record", sessionAttrs(sess)...)
+		res, err := s.SendWithTimeoutContext(ctx, "stop_record", nil, 60*time.Second)
 		if err == nil {
 			if url, ok := res["record_url"].(string); ok {
-				c.RecordURL = url
-				fmt.Printf("[SDK] Session Record URL: %s\n", c.RecordURL)
+				s.mu.Lock()
+				s.RecordURL = url
+				s.mu.Unlock()
+				log.LogAttrs(ctx, slog.LevelInfo, "session record", sessionAttrs(sess, slog.String("url", url))...)
 			}
 		}
 	}
 
 	// 3. Release Browser
-	fmt.Println("[SDK] Sending release command...")
-	res, err := c.Send("release_browser", nil)
+	res, err := s.SendContext(ctx, "release_browser", nil)
 	if err != nil {
-		fmt.Printf("[SDK ERROR] Error inside release: %v\n", err)
+		log.LogAttrs(ctx, slog.LevelError, "release failed", sessionAttrs(sess,
+			slog.Duration("latency", time.Since(start)),
+			slog.String("error", err.Error()))...)
+		failSpan(releaseSpan, err)
 		return map[string]interface{}{"status": "error", "error": err.Error()}, err
 	}
+	log.LogAttrs(ctx, slog.LevelInfo, "browser released", sessionAttrs(sess, slog.Duration("latency", time.Since(start)))...)
+	releaseSpan.End()
 
-	c.SessionData = res
+	released = true
+	s.mu.Lock()
+	s.SessionData = res
+	s.mu.Unlock()
 	return res, nil
 }
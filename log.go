@@ -0,0 +1,109 @@
+package isoautomate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// discardLogger is the default logger: the SDK is silent unless
+// Config.Logger or Client.SetLogger provides one.
+var discardLogger = slog.New(slog.DiscardHandler)
+
+// SetLogger makes the client and its sessions log to l; nil silences them
+// again. Every task is logged with its action, task ID, worker, browser ID,
+// latency and outcome (successes at Debug, assertion failures at Info,
+// worker-reported errors at Warn, timeouts and Redis errors at Error), and
+// session lifecycle events at Info. Set it before acquiring sessions.
+func (c *Client) SetLogger(l *slog.Logger) {
+	if l == nil {
+		l = discardLogger
+	}
+	c.logger = l
+}
+
+// Logger returns the client's logger.
+func (c *Client) Logger() *slog.Logger {
+	return c.logger
+}
+
+// Task outcomes, as logged in the "outcome" attribute and passed to
+// MetricsRecorder.ObserveTask.
+const (
+	OutcomeOK              = "ok"
+	OutcomeAssertionFailed = "assertion_failed"
+	OutcomeActionError     = "action_error"
+	OutcomeTimeout         = "timeout"
+	OutcomeCancelled       = "cancelled"
+	OutcomeError           = "error" // The task could not be sent or its result not read
+)
+
+// rpcOutcome classifies a finished task from its raw response or error,
+// returning the worker's error message for failed actions.
+func rpcOutcome(ctx context.Context, raw []byte, err error) (outcome, message string) {
+	switch {
+	case err == nil:
+	case ctx.Err() != nil:
+		return OutcomeCancelled, err.Error()
+	case errors.Is(err, ErrTimeout):
+		return OutcomeTimeout, err.Error()
+	default:
+		return OutcomeError, err.Error()
+	}
+
+	var resp struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+	}
+	if json.Unmarshal(raw, &resp) != nil {
+		return OutcomeError, "unparsable worker response"
+	}
+	switch resp.Status {
+	case "fail":
+		return OutcomeAssertionFailed, resp.Error
+	case "error":
+		return OutcomeActionError, resp.Error
+	}
+	return OutcomeOK, ""
+}
+
+// rpcLevels is the level each outcome is logged at.
+var rpcLevels = map[string]slog.Level{
+	OutcomeOK:              slog.LevelDebug,
+	OutcomeCancelled:       slog.LevelDebug,
+	OutcomeAssertionFailed: slog.LevelInfo,
+	OutcomeActionError:     slog.LevelWarn,
+	OutcomeTimeout:         slog.LevelError,
+	OutcomeError:           slog.LevelError,
+}
+
+// logRPC logs a finished task with its outcome (see rpcOutcome).
+func (c *Client) logRPC(ctx context.Context, task *pendingTask, outcome, message string) {
+	attrs := []slog.Attr{
+		slog.String("action", task.Action),
+		slog.String("task_id", task.ID),
+		slog.String("worker", task.Worker),
+		slog.String("browser_id", task.BrowserID),
+		slog.Duration("latency", time.Since(task.started)),
+		slog.String("outcome", outcome),
+	}
+	if message != "" {
+		attrs = append(attrs, slog.String("error", message))
+	}
+	c.logger.LogAttrs(context.WithoutCancel(ctx), rpcLevels[outcome], "task", attrs...)
+}
+
+// sessionAttrs identifies a session in lifecycle log records.
+func sessionAttrs(sess *Session, more ...slog.Attr) []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("worker", sess.WorkerName),
+		slog.String("browser_id", sess.BrowserID),
+		slog.String("browser_type", sess.BrowserType),
+	}
+	if sess.ProfileName != "" {
+		attrs = append(attrs, slog.String("profile", sess.ProfileName))
+	}
+	return append(attrs, more...)
+}
@@ -0,0 +1,815 @@
+package isoautomate
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryTransport is an in-process Transport with the same semantics as
+// RedisTransport. It is meant for unit tests and single-process deployments
+// where the "worker" runs in the same binary.
+//
+// Fleet setup and the worker side are driven through AddBrowsers, NextTask
+// and Reply.
+type MemoryTransport struct {
+	mu              sync.Mutex
+	lists           map[string]*memoryList
+	abandoned       map[string]time.Time // Result key -> until when late results for it are dropped
+	workers         map[string]struct{}
+	free            map[string]map[string]struct{} // "<worker>:<type>" -> browser IDs
+	busy            map[string]map[string]struct{}
+	leases          map[string]Lease // Lease.member() -> lease
+	cancelled       map[string]struct{}
+	queues          map[string]*memoryQueue      // Browser type -> acquire wait queue
+	released        map[string]chan struct{}     // Browser type -> broadcast, closed on every release
+	labels          map[string]map[string]string // Worker -> labels
+	profileWorkers  map[string]string            // Profile ID -> last worker
+	rr              map[string]int               // Browser type -> round-robin counter
+	profiles        map[string]Profile           // Profile name -> profile
+	deletedProfiles map[string]struct{}          // IDs of deleted profiles
+	profileLocks    map[string]ProfileLock       // Profile ID -> lock
+}
+
+// memoryQueue is an acquire wait queue: tickets in arrival order, each
+// with its waiter's request and the time it expires unless refreshed.
+type memoryQueue struct {
+	tickets []string
+	waiters map[string]memoryWaiter
+	ema     time.Duration // Pace of the queue, see RedisTransport
+	last    time.Time
+}
+
+type memoryWaiter struct {
+	req     AcquireRequest
+	expires time.Time
+}
+
+var _ Transport = (*MemoryTransport)(nil)
+
+// memoryList is a FIFO with a broadcast channel that is closed (and
+// replaced) on every push, waking all blocked readers.
+type memoryList struct {
+	items   [][]byte
+	notify  chan struct{}
+	waiters int // Blocked pops; the list is kept while any is waiting
+}
+
+// NewMemoryTransport returns an empty in-memory fleet.
+func NewMemoryTransport() *MemoryTransport {
+	return &MemoryTransport{
+		lists:           make(map[string]*memoryList),
+		abandoned:       make(map[string]time.Time),
+		workers:         make(map[string]struct{}),
+		free:            make(map[string]map[string]struct{}),
+		busy:            make(map[string]map[string]struct{}),
+		leases:          make(map[string]Lease),
+		cancelled:       make(map[string]struct{}),
+		queues:          make(map[string]*memoryQueue),
+		released:        make(map[string]chan struct{}),
+		labels:          make(map[string]map[string]string),
+		profileWorkers:  make(map[string]string),
+		rr:              make(map[string]int),
+		profiles:        make(map[string]Profile),
+		deletedProfiles: make(map[string]struct{}),
+		profileLocks:    make(map[string]ProfileLock),
+	}
+}
+
+// --- Fleet & Worker Side ---
+
+// AddBrowsers registers worker and makes the given browsers free for
+// browserType.
+func (t *MemoryTransport) AddBrowsers(worker, browserType string, browserIDs ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.workers[worker] = struct{}{}
+	key := worker + ":" + browserType
+	if t.free[key] == nil {
+		t.free[key] = make(map[string]struct{})
+	}
+	for _, id := range browserIDs {
+		t.free[key][id] = struct{}{}
+	}
+}
+
+// SetWorkerLabels replaces the labels a worker advertises for
+// AcquireOptions.Labels.
+func (t *MemoryTransport) SetWorkerLabels(worker string, labels map[string]string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.labels[worker] = labels
+}
+
+// Browsers returns the sorted free and busy browser IDs of a worker.
+func (t *MemoryTransport) Browsers(worker, browserType string) (free, busy []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := worker + ":" + browserType
+	return sortedKeys(t.free[key]), sortedKeys(t.busy[key])
+}
+
+// NextTask blocks until a task is queued for worker and returns it.
+func (t *MemoryTransport) NextTask(ctx context.Context, worker string) (TaskPayload, error) {
+	var task TaskPayload
+	raw, err := t.pop(ctx, worker+":tasks", 0)
+	if err != nil {
+		return task, err
+	}
+	err = json.Unmarshal(raw, &task)
+	return task, err
+}
+
+// Reply delivers a worker response for a task.
+func (t *MemoryTransport) Reply(resultKey string, response interface{}) error {
+	data, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	t.push(resultKey, data)
+	return nil
+}
+
+// IsCancelled reports whether the SDK abandoned the task.
+func (t *MemoryTransport) IsCancelled(taskID string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, ok := t.cancelled[taskID]
+	return ok
+}
+
+// --- Transport ---
+
+func (t *MemoryTransport) Enqueue(ctx context.Context, worker string, task []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	t.push(worker+":tasks", task)
+	return nil
+}
+
+func (t *MemoryTransport) Await(ctx context.Context, resultKey string, timeout time.Duration) ([]byte, error) {
+	item, err := t.pop(ctx, resultKey, timeout)
+	if err != nil {
+		t.abandon(resultKey)
+	}
+	return item, err
+}
+
+func (t *MemoryTransport) CancelTask(ctx context.Context, taskID string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.cancelled[taskID] = struct{}{}
+	return nil
+}
+
+func (t *MemoryTransport) AcquireBrowser(ctx context.Context, req AcquireRequest) (Lease, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	workers := sortedKeys(t.workers)
+	rand.Shuffle(len(workers), func(i, j int) {
+		workers[i], workers[j] = workers[j], workers[i]
+	})
+	workers = t.selectWorkersLocked(workers, req)
+
+	// Waiters are served first-come, first-served: a worker that a waiter
+	// ahead of us may use is left to it.
+	q := t.queueLocked(req.BrowserType)
+	ahead, queued := q.aheadLocked(req.QueueTicket, time.Now())
+	claimed := make(map[string]bool)
+	for _, waiter := range ahead {
+		for _, worker := range t.filterWorkersLocked(workers, waiter) {
+			claimed[worker] = true
+		}
+	}
+
+	for _, worker := range workers {
+		if claimed[worker] {
+			continue
+		}
+		key := worker + ":" + req.BrowserType
+		for bid := range t.free[key] {
+			t.moveLocked(t.free, t.busy, key, bid)
+			lease := Lease{
+				Worker:      worker,
+				BrowserType: req.BrowserType,
+				BrowserID:   bid,
+				Token:       req.LeaseToken,
+				Expires:     time.Now().Add(req.LeaseTTL),
+			}
+			t.leases[lease.member()] = lease
+			if req.ProfileID != "" {
+				t.profileWorkers[req.ProfileID] = worker
+			}
+			if queued {
+				q.serveLocked(req.QueueTicket, time.Now())
+			}
+			return lease, nil
+		}
+	}
+	return Lease{}, ErrNoBrowsersAvailable
+}
+
+func (t *MemoryTransport) ReleaseBrowser(ctx context.Context, worker, browserType, browserID string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	lease := Lease{Worker: worker, BrowserType: browserType, BrowserID: browserID}
+	delete(t.leases, lease.member())
+	if t.moveLocked(t.busy, t.free, worker+":"+browserType, browserID) {
+		t.notifyLocked(browserType)
+	}
+	return nil
+}
+
+func (t *MemoryTransport) ReleaseLease(ctx context.Context, lease Lease) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if held, ok := t.leases[lease.member()]; !ok || held.Token != lease.Token {
+		return ErrLeaseLost
+	}
+	delete(t.leases, lease.member())
+	if t.moveLocked(t.busy, t.free, lease.Worker+":"+lease.BrowserType, lease.BrowserID) {
+		t.notifyLocked(lease.BrowserType)
+	}
+	return nil
+}
+
+func (t *MemoryTransport) BrowserBusy(ctx context.Context, worker, browserType, browserID string) (bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, ok := t.busy[worker+":"+browserType][browserID]
+	return ok, nil
+}
+
+func (t *MemoryTransport) BrowserCounts(ctx context.Context) ([]BrowserCount, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	keys := make(map[string]struct{})
+	for key := range t.free {
+		keys[key] = struct{}{}
+	}
+	for key := range t.busy {
+		keys[key] = struct{}{}
+	}
+
+	var counts []BrowserCount
+	for key := range keys {
+		i := strings.LastIndexByte(key, ':')
+		worker, browserType := key[:i], key[i+1:]
+		free, busy := len(t.free[key]), len(t.busy[key])
+		if _, ok := t.workers[worker]; ok && free+busy > 0 {
+			counts = append(counts, BrowserCount{Worker: worker, BrowserType: browserType, Free: free, Busy: busy})
+		}
+	}
+	sortBrowserCounts(counts)
+	return counts, nil
+}
+
+func (t *MemoryTransport) RenewLease(ctx context.Context, lease Lease, ttl time.Duration) (time.Time, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	held, ok := t.leases[lease.member()]
+	if !ok || held.Token != lease.Token {
+		return time.Time{}, ErrLeaseLost
+	}
+	held.Expires = time.Now().Add(ttl)
+	t.leases[lease.member()] = held
+	return held.Expires, nil
+}
+
+func (t *MemoryTransport) DropLease(ctx context.Context, lease Lease) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if held, ok := t.leases[lease.member()]; ok && held.Token == lease.Token {
+		delete(t.leases, lease.member())
+	}
+	return nil
+}
+
+func (t *MemoryTransport) ReapExpiredLeases(ctx context.Context, token string, hold time.Duration) ([]Lease, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	var reaped []Lease
+	for member, lease := range t.leases {
+		if lease.Expires.After(now) {
+			continue
+		}
+		if _, busy := t.busy[lease.Worker+":"+lease.BrowserType][lease.BrowserID]; !busy {
+			delete(t.leases, member)
+			continue
+		}
+		t.leases[member] = Lease{
+			Worker:      lease.Worker,
+			BrowserType: lease.BrowserType,
+			BrowserID:   lease.BrowserID,
+			Token:       token,
+			Expires:     now.Add(hold),
+		}
+		lease.Token = ""
+		reaped = append(reaped, lease)
+	}
+	return reaped, nil
+}
+
+func (t *MemoryTransport) JoinQueue(ctx context.Context, req AcquireRequest, ttl time.Duration) (QueueStatus, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	ticket := req.QueueTicket
+	q := t.queueLocked(req.BrowserType)
+	if _, ok := q.waiters[ticket]; !ok {
+		q.tickets = append(q.tickets, ticket)
+	}
+	q.waiters[ticket] = memoryWaiter{req: req, expires: now.Add(ttl)}
+
+	pos := 0
+	live := q.tickets[:0]
+	for _, id := range q.tickets {
+		if q.waiters[id].expires.Before(now) {
+			delete(q.waiters, id)
+			continue
+		}
+		live = append(live, id)
+		if pos == 0 && id == ticket {
+			pos = len(live)
+		}
+	}
+	q.tickets = live
+	return QueueStatus{Position: pos, EstimatedWait: time.Duration(pos) * q.ema}, nil
+}
+
+func (t *MemoryTransport) LeaveQueue(ctx context.Context, browserType, ticket string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	q := t.queueLocked(browserType)
+	if _, ok := q.waiters[ticket]; !ok {
+		return nil
+	}
+	q.removeLocked(ticket)
+	t.notifyLocked(browserType)
+	return nil
+}
+
+func (t *MemoryTransport) WatchReleases(ctx context.Context, browserType string) (<-chan struct{}, error) {
+	out := make(chan struct{}, 1)
+	go func() {
+		for {
+			t.mu.Lock()
+			released := t.releasedLocked(browserType)
+			t.mu.Unlock()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-released:
+			}
+			select {
+			case out <- struct{}{}:
+			default: // A signal is already pending
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (t *MemoryTransport) NotifyReleased(ctx context.Context, browserType string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.notifyLocked(browserType)
+	return nil
+}
+
+func (t *MemoryTransport) CreateProfile(ctx context.Context, p Profile) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.profiles[p.Name]; ok {
+		return ErrProfileExists
+	}
+	t.profiles[p.Name] = p
+	return nil
+}
+
+func (t *MemoryTransport) GetProfile(ctx context.Context, name string) (Profile, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.profiles[name]
+	if !ok {
+		return Profile{}, ErrProfileNotFound
+	}
+	return p, nil
+}
+
+func (t *MemoryTransport) ListProfiles(ctx context.Context) ([]Profile, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	profiles := make([]Profile, 0, len(t.profiles))
+	for _, p := range t.profiles {
+		profiles = append(profiles, p)
+	}
+	return profiles, nil
+}
+
+func (t *MemoryTransport) RenameProfile(ctx context.Context, oldName, newName string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.profiles[oldName]
+	if !ok {
+		return ErrProfileNotFound
+	}
+	if _, taken := t.profiles[newName]; taken {
+		return ErrProfileExists
+	}
+	delete(t.profiles, oldName)
+	p.Name = newName
+	t.profiles[newName] = p
+	return nil
+}
+
+func (t *MemoryTransport) DeleteProfile(ctx context.Context, name string, force bool) (ProfileLock, bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.profiles[name]
+	if !ok {
+		return ProfileLock{}, false, ErrProfileNotFound
+	}
+	held, locked := t.profileLocks[p.ID]
+	locked = locked && held.Expires.After(time.Now())
+	if locked && !force {
+		return ProfileLock{}, false, ErrProfileLocked
+	}
+	delete(t.profileLocks, p.ID)
+	delete(t.profiles, name)
+	delete(t.profileWorkers, p.ID)
+	t.deletedProfiles[p.ID] = struct{}{}
+	if !locked {
+		return ProfileLock{}, false, nil
+	}
+	return held, true, nil
+}
+
+func (t *MemoryTransport) TouchProfile(ctx context.Context, name, browserType string, at time.Time) (Profile, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.profiles[name]
+	if !ok {
+		return Profile{}, ErrProfileNotFound
+	}
+	updated := p
+	updated.LastUsed = at
+	if updated.BrowserType == "" {
+		updated.BrowserType = browserType
+	}
+	updated.ClonedFrom = ""
+	t.profiles[name] = updated
+	return p, nil
+}
+
+func (t *MemoryTransport) LockProfile(ctx context.Context, lock ProfileLock, ttl time.Duration) (ProfileLock, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	if held, ok := t.profileLocks[lock.ProfileID]; ok && held.Expires.After(now) {
+		return held, ErrProfileLocked
+	}
+	lock.Expires = now.Add(ttl)
+	t.profileLocks[lock.ProfileID] = lock
+	return lock, nil
+}
+
+func (t *MemoryTransport) RenewProfileLock(ctx context.Context, lock ProfileLock, ttl time.Duration) (time.Time, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	held, ok := t.profileLocks[lock.ProfileID]
+	if !ok || held.Token != lock.Token || !held.Expires.After(now) {
+		return time.Time{}, ErrLeaseLost
+	}
+	held.Expires = now.Add(ttl)
+	t.profileLocks[lock.ProfileID] = held
+	return held.Expires, nil
+}
+
+func (t *MemoryTransport) UnlockProfile(ctx context.Context, lock ProfileLock) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if held, ok := t.profileLocks[lock.ProfileID]; ok && held.Token == lock.Token {
+		delete(t.profileLocks, lock.ProfileID)
+	}
+	return nil
+}
+
+func (t *MemoryTransport) BreakProfileLock(ctx context.Context, profileID string) (ProfileLock, bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	held, ok := t.profileLocks[profileID]
+	delete(t.profileLocks, profileID)
+	if !ok || !held.Expires.After(time.Now()) {
+		return ProfileLock{}, false, nil
+	}
+	return held, true, nil
+}
+
+func (t *MemoryTransport) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (t *MemoryTransport) Close() error {
+	return nil
+}
+
+// --- Helpers ---
+
+// moveLocked moves a browser between two set maps (free/busy) and reports
+// whether it was in the source set. t.mu must be held.
+func (t *MemoryTransport) moveLocked(from, to map[string]map[string]struct{}, key, browserID string) bool {
+	if _, ok := from[key][browserID]; !ok {
+		return false
+	}
+	delete(from[key], browserID)
+	if to[key] == nil {
+		to[key] = make(map[string]struct{})
+	}
+	to[key][browserID] = struct{}{}
+	return true
+}
+
+// selectWorkersLocked applies an acquire's worker selection, like the
+// acquire script does.
+func (t *MemoryTransport) selectWorkersLocked(workers []string, req AcquireRequest) []string {
+	out := t.orderWorkersLocked(t.filterWorkersLocked(workers, req), req)
+
+	if preferred, ok := t.profileWorkers[req.Affinity]; ok && req.Affinity != "" {
+		if i := slices.Index(out, preferred); i > 0 {
+			out = append([]string{preferred}, slices.Delete(out, i, i+1)...)
+		}
+	}
+	return out
+}
+
+// filterWorkersLocked returns the workers an acquire's selection allows, in
+// their original order.
+func (t *MemoryTransport) filterWorkersLocked(workers []string, req AcquireRequest) []string {
+	var out []string
+	for _, worker := range workers {
+		if len(req.Workers) > 0 && !slices.Contains(req.Workers, worker) {
+			continue
+		}
+		if slices.Contains(req.Exclude, worker) {
+			continue
+		}
+		matches := true
+		for k, v := range req.Labels {
+			if got, ok := t.labels[worker][k]; !ok || got != v {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			out = append(out, worker)
+		}
+	}
+	return out
+}
+
+// orderWorkersLocked sorts the selected workers by the acquire's strategy.
+func (t *MemoryTransport) orderWorkersLocked(workers []string, req AcquireRequest) []string {
+	switch req.Strategy {
+	case StrategyLeastBusy:
+		slices.SortStableFunc(workers, func(a, b string) int {
+			return len(t.busy[a+":"+req.BrowserType]) - len(t.busy[b+":"+req.BrowserType])
+		})
+	case StrategyRoundRobin:
+		if len(workers) == 0 {
+			break
+		}
+		sort.Strings(workers)
+		start := t.rr[req.BrowserType] % len(workers)
+		t.rr[req.BrowserType]++
+		workers = append(slices.Clone(workers[start:]), workers[:start]...)
+	case StrategyWeighted:
+		keys := make(map[string]float64, len(workers))
+		for _, worker := range workers {
+			key := worker + ":" + req.BrowserType
+			w := float64(len(t.free[key]) + len(t.busy[key]))
+			if c, err := strconv.ParseFloat(t.labels[worker]["capacity"], 64); err == nil {
+				w = c
+			}
+			keys[worker] = -1
+			if w > 0 {
+				keys[worker] = math.Pow(rand.Float64(), 1/w)
+			}
+		}
+		slices.SortFunc(workers, func(a, b string) int {
+			return cmp.Compare(keys[b], keys[a])
+		})
+	}
+	return workers
+}
+
+func (t *MemoryTransport) queueLocked(browserType string) *memoryQueue {
+	q, ok := t.queues[browserType]
+	if !ok {
+		q = &memoryQueue{waiters: make(map[string]memoryWaiter)}
+		t.queues[browserType] = q
+	}
+	return q
+}
+
+func (t *MemoryTransport) releasedLocked(browserType string) chan struct{} {
+	ch, ok := t.released[browserType]
+	if !ok {
+		ch = make(chan struct{})
+		t.released[browserType] = ch
+	}
+	return ch
+}
+
+// notifyLocked wakes the browser type's watchers. t.mu must be held.
+func (t *MemoryTransport) notifyLocked(browserType string) {
+	close(t.releasedLocked(browserType))
+	t.released[browserType] = make(chan struct{})
+}
+
+// aheadLocked drops expired tickets and returns the requests of the
+// waiters queued before ticket, and whether ticket is queued.
+func (q *memoryQueue) aheadLocked(ticket string, now time.Time) ([]AcquireRequest, bool) {
+	var ahead []AcquireRequest
+	live := q.tickets[:0]
+	queued := false
+	for _, id := range q.tickets {
+		w := q.waiters[id]
+		if !w.expires.After(now) {
+			delete(q.waiters, id)
+			continue
+		}
+		live = append(live, id)
+		if id == ticket {
+			queued = true
+		} else if !queued {
+			ahead = append(ahead, w.req)
+		}
+	}
+	q.tickets = live
+	return ahead, queued
+}
+
+// removeLocked drops ticket from the queue.
+func (q *memoryQueue) removeLocked(ticket string) {
+	delete(q.waiters, ticket)
+	if i := slices.Index(q.tickets, ticket); i >= 0 {
+		q.tickets = slices.Delete(q.tickets, i, i+1)
+	}
+}
+
+// serveLocked removes ticket after it got a browser and updates the pace of
+// the queue.
+func (q *memoryQueue) serveLocked(ticket string, now time.Time) {
+	q.removeLocked(ticket)
+
+	if !q.last.IsZero() {
+		gap := now.Sub(q.last)
+		if q.ema == 0 {
+			q.ema = gap
+		}
+		q.ema = q.ema*7/10 + gap*3/10
+	}
+	if len(q.tickets) > 0 {
+		q.last = now
+	} else {
+		q.last = time.Time{}
+	}
+}
+
+func (t *MemoryTransport) list(key string) *memoryList {
+	l, ok := t.lists[key]
+	if !ok {
+		l = &memoryList{notify: make(chan struct{})}
+		t.lists[key] = l
+	}
+	return l
+}
+
+func (t *MemoryTransport) push(key string, item []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if until, ok := t.abandoned[key]; ok {
+		if time.Now().Before(until) {
+			return // Nobody will read it
+		}
+		delete(t.abandoned, key)
+	}
+	l := t.list(key)
+	l.items = append(l.items, item)
+	close(l.notify)
+	l.notify = make(chan struct{})
+}
+
+// pop removes the head of a list, blocking until an item arrives, timeout
+// elapses (0 waits forever) or ctx is done.
+func (t *MemoryTransport) pop(ctx context.Context, key string, timeout time.Duration) ([]byte, error) {
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	t.mu.Lock()
+	l := t.list(key)
+	l.waiters++
+	defer func() {
+		l.waiters--
+		t.dropIfUnusedLocked(key, l)
+		t.mu.Unlock()
+	}()
+
+	for {
+		if len(l.items) > 0 {
+			item := l.items[0]
+			l.items = l.items[1:]
+			return item, nil
+		}
+		notify := l.notify
+		t.mu.Unlock()
+
+		select {
+		case <-notify:
+			t.mu.Lock()
+		case <-expired:
+			t.mu.Lock()
+			return nil, ErrTimeout
+		case <-ctx.Done():
+			t.mu.Lock()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// abandon drops the result a worker pushes onto resultKey after its Await
+// gave up, for as long as the worker honours the task's cancel marker. In
+// Redis the key expires instead (see RedisTransport.Await).
+func (t *MemoryTransport) abandon(resultKey string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	for key, until := range t.abandoned {
+		if !now.Before(until) {
+			delete(t.abandoned, key)
+		}
+	}
+	t.abandoned[resultKey] = now.Add(cancelMarkerTTL)
+}
+
+// dropIfUnusedLocked forgets an empty list nobody waits on, so abandoned
+// result keys do not pile up. t.mu must be held.
+func (t *MemoryTransport) dropIfUnusedLocked(key string, l *memoryList) {
+	if len(l.items) == 0 && l.waiters == 0 && t.lists[key] == l {
+		delete(t.lists, key)
+	}
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,30 @@
+package isoautomate
+
+import "time"
+
+// MetricsRecorder receives a client's task and session events. The SDK
+// itself records nothing; the isometrics package implements it for
+// Prometheus. Hand one to clients through Config.Metrics or
+// Client.SetMetrics. Its methods are called from every goroutine using the
+// client, so they must be safe for concurrent use.
+type MetricsRecorder interface {
+	// ObserveTask records a finished task: its action, the worker it was
+	// sent to, its outcome (one of the Outcome constants) and the time from
+	// sending it until its result was in.
+	ObserveTask(action, worker, outcome string, latency time.Duration)
+
+	// TransportRetried counts a retry of a transport operation ("enqueue",
+	// "await").
+	TransportRetried(operation string)
+
+	// SessionsHeld moves the count of sessions acquired or attached and not
+	// released yet by delta.
+	SessionsHeld(delta int)
+}
+
+// SetMetrics makes the client record its tasks and sessions in m; nil stops
+// recording. Set it before acquiring sessions so the sessions count covers
+// their release too.
+func (c *Client) SetMetrics(m MetricsRecorder) {
+	c.metrics = m
+}
@@ -0,0 +1,417 @@
+package isoautomate
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Pool defaults
+const (
+	DefaultPoolIdleTimeout  = 5 * time.Minute
+	DefaultPoolResetTimeout = 30 * time.Second
+	DefaultPoolResetURL     = "about:blank"
+)
+
+// PoolConfig configures a Pool.
+type PoolConfig struct {
+	BrowserType string
+
+	// Size is the number of sessions kept acquired. NewPool pre-warms them
+	// and the pool tops them up again after a session is discarded.
+	Size int
+
+	// MaxSize bounds the sessions held at once, idle or borrowed. Borrow
+	// waits for a Return once it is reached. 0 means no bound: Borrow
+	// acquires extra sessions on demand.
+	MaxSize int
+
+	// IdleTimeout is how long an idle session above Size is kept before it
+	// is released back to the fleet (default DefaultPoolIdleTimeout).
+	IdleTimeout time.Duration
+
+	// ResetURL is opened on every returned session (default about:blank).
+	ResetURL string
+
+	// Reset, if set, replaces the default reset (clear cookies, open
+	// ResetURL). A session whose reset fails is released, not reused.
+	Reset func(ctx context.Context, s *BrowserSession) error
+
+	// ResetTimeout bounds the reset on Return (default DefaultPoolResetTimeout).
+	ResetTimeout time.Duration
+
+	// Acquire is the template for the sessions the pool acquires (worker
+	// selection, labels). Its BrowserType is taken from BrowserType above
+	// and its Wait is ignored.
+	Acquire AcquireOptions
+}
+
+// PoolStats is a snapshot of a pool's sessions.
+type PoolStats struct {
+	Idle     int // Acquired and ready to borrow
+	InUse    int // Borrowed and not returned yet
+	Pending  int // Being acquired
+	Borrowed int // Borrow calls served since the pool was created
+}
+
+// Pool keeps browser sessions of one type acquired so jobs can borrow a
+// ready browser instead of paying for Acquire each time. Pooled sessions
+// stay busy in the fleet's accounting and keep renewing their leases while
+// idle. Use one pool per browser type.
+type Pool struct {
+	c   *Client
+	cfg PoolConfig
+
+	mu       sync.Mutex
+	idle     []pooledSession // Oldest first; Borrow takes the newest
+	inUse    map[*BrowserSession]bool
+	pending  int
+	borrowed int
+	closed   bool
+	changed  chan struct{} // Closed and replaced whenever capacity frees up
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+type pooledSession struct {
+	s     *BrowserSession
+	since time.Time // When it was last returned
+}
+
+// NewPool creates a pool and acquires cfg.Size sessions up front. If any of
+// them cannot be acquired, the ones that were are released and the error is
+// returned.
+func (c *Client) NewPool(ctx context.Context, cfg PoolConfig) (*Pool, error) {
+	if cfg.BrowserType == "" {
+		return nil, NewBrowserError("Pool needs a browser type")
+	}
+	if cfg.Size < 0 || cfg.MaxSize < 0 || (cfg.MaxSize > 0 && cfg.MaxSize < cfg.Size) {
+		return nil, NewBrowserError("Invalid pool size %d (max %d)", cfg.Size, cfg.MaxSize)
+	}
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = DefaultPoolIdleTimeout
+	}
+	if cfg.ResetURL == "" {
+		cfg.ResetURL = DefaultPoolResetURL
+	}
+	if cfg.ResetTimeout <= 0 {
+		cfg.ResetTimeout = DefaultPoolResetTimeout
+	}
+	cfg.Acquire.BrowserType = cfg.BrowserType
+	cfg.Acquire.Wait = nil
+
+	p := &Pool{
+		c:       c,
+		cfg:     cfg,
+		inUse:   make(map[*BrowserSession]bool),
+		changed: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	if err := p.fill(ctx); err != nil {
+		_ = p.Close(context.WithoutCancel(ctx))
+		return nil, err
+	}
+
+	maintainCtx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	go p.maintain(maintainCtx)
+	return p, nil
+}
+
+// Borrow hands out an idle session, acquiring a new one if none is idle.
+// Once MaxSize sessions are held it waits for a Return until ctx is done.
+// Every borrowed session must be given back with Return.
+func (p *Pool) Borrow(ctx context.Context) (*BrowserSession, error) {
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return nil, newError(ErrPoolClosed, nil, "Pool for '%s' is closed", p.cfg.BrowserType)
+		}
+
+		// 1. Reuse the most recently returned session
+		if n := len(p.idle); n > 0 {
+			s := p.idle[n-1].s
+			p.idle = p.idle[:n-1]
+			if _, err := s.Lease(); err != nil {
+				// Reclaimed while idle: the browser may already be someone else's
+				p.signalLocked()
+				p.mu.Unlock()
+				p.discard(ctx, s)
+				continue
+			}
+			p.inUse[s] = true
+			p.borrowed++
+			p.mu.Unlock()
+			return s, nil
+		}
+
+		// 2. Grow the pool
+		if p.cfg.MaxSize == 0 || p.totalLocked() < p.cfg.MaxSize {
+			p.pending++
+			p.mu.Unlock()
+
+			s := newBrowserSession(p.c)
+			err := p.c.acquire(ctx, p.cfg.Acquire, s)
+
+			p.mu.Lock()
+			p.pending--
+			if err != nil {
+				p.signalLocked()
+				p.mu.Unlock()
+				return nil, err
+			}
+			if p.closed {
+				p.mu.Unlock()
+				p.discard(ctx, s)
+				return nil, newError(ErrPoolClosed, nil, "Pool for '%s' is closed", p.cfg.BrowserType)
+			}
+			p.inUse[s] = true
+			p.borrowed++
+			p.mu.Unlock()
+			return s, nil
+		}
+
+		// 3. Wait for a Return
+		changed := p.changed
+		p.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, newError(nil, ctx.Err(), "Gave up waiting for a pooled '%s' browser: %v", p.cfg.BrowserType, ctx.Err())
+		}
+	}
+}
+
+// Return gives a borrowed session back. The session is health-checked and
+// reset (cookies cleared, ResetURL opened) before it can be borrowed again;
+// if that fails, the session is released and the error is returned. The
+// session must not be used after Return.
+func (p *Pool) Return(s *BrowserSession) error {
+	p.mu.Lock()
+	if !p.inUse[s] {
+		p.mu.Unlock()
+		return NewBrowserError("Session was not borrowed from this pool")
+	}
+	closed := p.closed
+	p.mu.Unlock()
+
+	var err error
+	if !closed {
+		ctx, cancel := context.WithTimeout(context.Background(), p.cfg.ResetTimeout)
+		err = p.reset(ctx, s)
+		cancel()
+	}
+
+	p.mu.Lock()
+	delete(p.inUse, s)
+	keep := err == nil && !p.closed
+	if keep {
+		p.idle = append(p.idle, pooledSession{s: s, since: time.Now()})
+	}
+	p.signalLocked()
+	p.mu.Unlock()
+
+	if !keep {
+		p.discard(context.Background(), s)
+	}
+	if err != nil {
+		return newError(nil, err, "Pooled session failed its health check and was released: %v", err)
+	}
+	return nil
+}
+
+// Stats returns a snapshot of the pool.
+func (p *Pool) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return PoolStats{Idle: len(p.idle), InUse: len(p.inUse), Pending: p.pending, Borrowed: p.borrowed}
+}
+
+// Close releases the idle sessions and stops the pool. Sessions still
+// borrowed are released when they are returned. Borrow fails with
+// ErrPoolClosed afterwards.
+func (p *Pool) Close(ctx context.Context) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	idle := p.idle
+	p.idle = nil
+	p.signalLocked()
+	p.mu.Unlock()
+
+	if p.cancel != nil {
+		p.cancel()
+		<-p.done
+	}
+
+	sessions := make([]*BrowserSession, len(idle))
+	for i, ps := range idle {
+		sessions[i] = ps.s
+	}
+	return releaseAll(ctx, sessions)
+}
+
+// reset health-checks a returned session and wipes its state.
+func (p *Pool) reset(ctx context.Context, s *BrowserSession) error {
+	if _, err := s.Lease(); err != nil {
+		return err
+	}
+	if p.cfg.Reset != nil {
+		return p.cfg.Reset(ctx, s)
+	}
+	if _, err := s.ClearCookiesContext(ctx); err != nil {
+		return err
+	}
+	_, err := s.OpenURLContext(ctx, p.cfg.ResetURL)
+	return err
+}
+
+// discard releases a session the pool no longer holds, best effort.
+func (p *Pool) discard(ctx context.Context, s *BrowserSession) {
+	releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), p.cfg.ResetTimeout)
+	defer cancel()
+
+	_, _ = s.ReleaseContext(releaseCtx)
+}
+
+func (p *Pool) totalLocked() int {
+	return len(p.idle) + len(p.inUse) + p.pending
+}
+
+// signalLocked wakes every Borrow waiting for capacity.
+func (p *Pool) signalLocked() {
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+// --- Maintenance ---
+
+// maintain periodically releases expired idle sessions and tops the pool
+// back up to Size.
+func (p *Pool) maintain(ctx context.Context) {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.cfg.IdleTimeout / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		p.evictIdle(ctx)
+		_ = p.fill(ctx)
+	}
+}
+
+// evictIdle releases idle sessions that lost their lease, and those above
+// Size that have been idle for longer than IdleTimeout.
+func (p *Pool) evictIdle(ctx context.Context) {
+	now := time.Now()
+
+	p.mu.Lock()
+	excess := p.totalLocked() - p.cfg.Size
+	var keep []pooledSession
+	var victims []*BrowserSession
+	for _, ps := range p.idle {
+		_, lost := ps.s.Lease()
+		expired := excess > 0 && now.Sub(ps.since) >= p.cfg.IdleTimeout
+		if lost != nil || expired {
+			victims = append(victims, ps.s)
+			excess--
+			continue
+		}
+		keep = append(keep, ps)
+	}
+	p.idle = keep
+	if len(victims) > 0 {
+		p.signalLocked()
+	}
+	p.mu.Unlock()
+
+	_ = releaseAll(ctx, victims)
+}
+
+// fill acquires sessions until the pool holds Size of them.
+func (p *Pool) fill(ctx context.Context) error {
+	p.mu.Lock()
+	need := p.cfg.Size - p.totalLocked()
+	if p.closed || need <= 0 {
+		p.mu.Unlock()
+		return nil
+	}
+	p.pending += need
+	p.mu.Unlock()
+
+	var (
+		wg   sync.WaitGroup
+		errs = make([]error, need)
+	)
+	for i := 0; i < need; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			s := newBrowserSession(p.c)
+			err := p.c.acquire(ctx, p.cfg.Acquire, s)
+
+			p.mu.Lock()
+			p.pending--
+			closed := p.closed
+			if err == nil && !closed {
+				p.idle = append(p.idle, pooledSession{s: s, since: time.Now()})
+			}
+			p.signalLocked()
+			p.mu.Unlock()
+
+			if err == nil && closed {
+				p.discard(ctx, s)
+			}
+			errs[i] = err
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// releaseAll releases sessions concurrently and reports the failures as a
+// *ReleaseError.
+func releaseAll(ctx context.Context, sessions []*BrowserSession) error {
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		failures []ReleaseFailure
+	)
+	for _, s := range sessions {
+		sess := s.current()
+		if sess == nil {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.ReleaseContext(ctx); err != nil {
+				mu.Lock()
+				failures = append(failures, ReleaseFailure{Session: *sess, Err: err})
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(failures) > 0 {
+		return &ReleaseError{Failures: failures}
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package isoautomate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultProfileLockPoll is how often a waiting Acquire retries the lock of
+// a profile that is in use.
+const DefaultProfileLockPoll = 500 * time.Millisecond
+
+// ProfileLock is an exclusive, time-limited hold on a persistent profile.
+// A session holds its profile's lock from Acquire until Release, so two
+// sessions never write to the same profile data at once. The lock is
+// renewed like the browser lease, and expires one lease TTL after its
+// holder stops renewing it.
+type ProfileLock struct {
+	ProfileID string    `json:"profile_id"`
+	Token     string    `json:"token"`  // Identifies the holder; only it can renew or unlock
+	Holder    string    `json:"holder"` // "<host>/<pid>" of the process that took the lock
+	Acquired  time.Time `json:"acquired"`
+	Expires   time.Time `json:"-"` // Reported by the store
+}
+
+// ProfileWaitOptions makes Acquire wait for a profile that another session
+// holds instead of failing at once with ErrProfileLocked (see
+// AcquireOptions.ProfileWait).
+type ProfileWaitOptions struct {
+	// Timeout bounds the wait. 0 waits until ctx is done.
+	Timeout time.Duration
+
+	// PollInterval is how often to retry the lock (default
+	// DefaultProfileLockPoll).
+	PollInterval time.Duration
+}
+
+// lockHolder names this process in the locks it takes.
+var lockHolder = func() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s/%d", host, os.Getpid())
+}()
+
+// lockProfile takes prof's lock for a new session, waiting for it if wait
+// is set, and keeps renewing it until the lock is unlocked.
+func (c *Client) lockProfile(ctx context.Context, prof Profile, wait *ProfileWaitOptions) (*profileLockKeeper, error) {
+	lock := ProfileLock{
+		ProfileID: prof.ID,
+		Token:     newHexID(),
+		Holder:    lockHolder,
+		Acquired:  time.Now().UTC(),
+	}
+
+	var ticker *time.Ticker
+	if wait != nil {
+		if wait.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, wait.Timeout)
+			defer cancel()
+		}
+		poll := wait.PollInterval
+		if poll <= 0 {
+			poll = DefaultProfileLockPoll
+		}
+		ticker = time.NewTicker(poll)
+		defer ticker.Stop()
+	}
+
+	for {
+		held, err := c.transport.LockProfile(ctx, lock, c.leaseTTL)
+		if err == nil {
+			return c.startProfileLock(held, c.leaseTTL), nil
+		}
+		if !errors.Is(err, ErrProfileLocked) {
+			if ctx.Err() != nil {
+				return nil, newError(ErrProfileLocked, ctx.Err(), "Profile '%s' is still in use: %v", prof.Name, ctx.Err())
+			}
+			return nil, newError(nil, err, "Failed to lock profile '%s': %v", prof.Name, err)
+		}
+		if ticker == nil {
+			return nil, newError(ErrProfileLocked, nil, "Profile '%s' is in use by %s (lock expires %s)",
+				prof.Name, held.Holder, held.Expires.Format(time.RFC3339))
+		}
+		c.logger.LogAttrs(ctx, slog.LevelDebug, "waiting for profile",
+			slog.String("profile", prof.Name),
+			slog.String("holder", held.Holder))
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil, newError(ErrProfileLocked, ctx.Err(), "Profile '%s' is still in use by %s: %v", prof.Name, held.Holder, ctx.Err())
+		}
+	}
+}
+
+// profileLockKeeper renews a held profile lock in the background.
+type profileLockKeeper struct {
+	c      *Client
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu   sync.Mutex
+	lock ProfileLock
+	err  error // Set if the lock was lost (expired or broken)
+}
+
+// startProfileLock begins renewing lock every ttl/3.
+func (c *Client) startProfileLock(lock ProfileLock, ttl time.Duration) *profileLockKeeper {
+	ctx, cancel := context.WithCancel(context.Background())
+	k := &profileLockKeeper{c: c, cancel: cancel, done: make(chan struct{}), lock: lock}
+
+	go func() {
+		defer close(k.done)
+
+		err := renewUntilLost(ctx, ttl, func(ctx context.Context) error {
+			expires, err := c.transport.RenewProfileLock(ctx, lock, ttl)
+			if err == nil {
+				k.mu.Lock()
+				k.lock.Expires = expires
+				k.mu.Unlock()
+			}
+			return err
+		})
+		if err != nil {
+			k.mu.Lock()
+			k.err = err
+			k.mu.Unlock()
+			c.logger.LogAttrs(context.Background(), slog.LevelWarn, "profile lock lost",
+				slog.String("profile_id", lock.ProfileID))
+		}
+	}()
+	return k
+}
+
+// stop ends the renewals and waits for the goroutine to exit. The lock
+// itself expires one TTL later.
+func (k *profileLockKeeper) stop() {
+	k.cancel()
+	<-k.done
+}
+
+// unlock stops the renewals and releases the lock, best effort.
+func (k *profileLockKeeper) unlock(ctx context.Context) {
+	k.stop()
+
+	unlockCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	_ = k.c.transport.UnlockProfile(unlockCtx, k.lock)
+}
+
+// BreakLock force-releases a profile's lock, for a holder that is stuck or
+// gone. The session that held the lock keeps its browser but can no longer
+// renew the lock. It returns the lock that was broken, or nil if the
+// profile was not locked.
+func (p *Profiles) BreakLock(ctx context.Context, name string) (*ProfileLock, error) {
+	prof, err := p.Describe(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	lock, ok, err := p.store.BreakProfileLock(ctx, prof.ID)
+	if err != nil {
+		return nil, newError(nil, err, "Failed to break the lock of profile '%s': %v", name, err)
+	}
+	if !ok {
+		return nil, nil
+	}
+	p.logger.LogAttrs(ctx, slog.LevelWarn, "profile lock broken",
+		slog.String("profile", name),
+		slog.String("holder", lock.Holder))
+	return &lock, nil
+}
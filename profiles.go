@@ -0,0 +1,237 @@
+package isoautomate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// DefaultProfile is the profile name to use where the SDK used to keep a
+// per-machine default profile.
+const DefaultProfile = "default"
+
+// Profile is a named persistent browser profile. Workers store the profile
+// data under ID, which never changes, so renaming a profile is cheap and
+// a profile can be used from any machine.
+type Profile struct {
+	Name        string    `json:"name"`
+	ID          string    `json:"id"`
+	BrowserType string    `json:"browser_type,omitempty"` // Set on first use if empty
+	Created     time.Time `json:"created"`
+	LastUsed    time.Time `json:"last_used,omitempty"`
+	ClonedFrom  string    `json:"cloned_from,omitempty"` // Source profile ID, until the clone is first used
+}
+
+// ProfileStore keeps the profile registry. Implementations must be safe for
+// concurrent use and make each call atomic.
+type ProfileStore interface {
+	// CreateProfile registers p, or returns ErrProfileExists if its name is taken.
+	CreateProfile(ctx context.Context, p Profile) error
+
+	// GetProfile returns a profile by name, or ErrProfileNotFound.
+	GetProfile(ctx context.Context, name string) (Profile, error)
+
+	// ListProfiles returns every profile.
+	ListProfiles(ctx context.Context) ([]Profile, error)
+
+	// RenameProfile renames a profile. It returns ErrProfileNotFound if
+	// oldName does not exist and ErrProfileExists if newName does.
+	RenameProfile(ctx context.Context, oldName, newName string) error
+
+	// DeleteProfile removes a profile and queues its ID for purging. It
+	// returns ErrProfileNotFound, or ErrProfileLocked (and changes nothing)
+	// while someone holds the profile's lock. With force it breaks the lock
+	// in the same atomic step instead, and returns the lock if there was one.
+	DeleteProfile(ctx context.Context, name string, force bool) (ProfileLock, bool, error)
+
+	// TouchProfile records a use of the profile at the given time (setting
+	// its browser type if it has none, and clearing ClonedFrom) and returns
+	// the profile as it was before.
+	TouchProfile(ctx context.Context, name, browserType string, at time.Time) (Profile, error)
+
+	// LockProfile takes the exclusive lock of lock.ProfileID for ttl and
+	// returns it with its expiry. If someone else holds it, it returns their
+	// lock together with ErrProfileLocked.
+	LockProfile(ctx context.Context, lock ProfileLock, ttl time.Duration) (ProfileLock, error)
+
+	// RenewProfileLock pushes the lock's expiry to now+ttl and returns it, or
+	// returns ErrLeaseLost if the lock expired or is held by someone else.
+	RenewProfileLock(ctx context.Context, lock ProfileLock, ttl time.Duration) (time.Time, error)
+
+	// UnlockProfile releases a lock the caller holds. Unlocking a lock that
+	// is gone is not an error.
+	UnlockProfile(ctx context.Context, lock ProfileLock) error
+
+	// BreakProfileLock releases the profile's lock whoever holds it, and
+	// returns the lock if there was one.
+	BreakProfileLock(ctx context.Context, profileID string) (ProfileLock, bool, error)
+}
+
+// Profiles manages the named persistent profiles shared by every client of
+// the fleet. Get one with Client.Profiles.
+type Profiles struct {
+	store  ProfileStore
+	logger *slog.Logger
+}
+
+// Profiles returns the profile registry.
+func (c *Client) Profiles() *Profiles {
+	return &Profiles{store: c.transport, logger: c.logger}
+}
+
+// Create registers a new, empty profile. browserType may be empty; it is
+// then set by the first session that uses the profile.
+func (p *Profiles) Create(ctx context.Context, name, browserType string) (Profile, error) {
+	return p.create(ctx, Profile{Name: name, BrowserType: browserType})
+}
+
+// Import registers a profile whose data workers already hold under id,
+// such as the ID from a former .iso_profiles/default_profile.id file.
+func (p *Profiles) Import(ctx context.Context, name, id, browserType string) (Profile, error) {
+	if id == "" {
+		return Profile{}, NewBrowserError("Profile ID is required")
+	}
+	return p.create(ctx, Profile{Name: name, ID: id, BrowserType: browserType})
+}
+
+// List returns every profile, sorted by name.
+func (p *Profiles) List(ctx context.Context) ([]Profile, error) {
+	profiles, err := p.store.ListProfiles(ctx)
+	if err != nil {
+		return nil, newError(nil, err, "Failed to list profiles: %v", err)
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+	return profiles, nil
+}
+
+// Describe returns a profile's metadata.
+func (p *Profiles) Describe(ctx context.Context, name string) (Profile, error) {
+	prof, err := p.store.GetProfile(ctx, name)
+	if err != nil {
+		return Profile{}, profileError(name, err)
+	}
+	return prof, nil
+}
+
+// Clone creates dst as a copy of src. The worker that first runs dst seeds
+// it from src's data.
+func (p *Profiles) Clone(ctx context.Context, src, dst string) (Profile, error) {
+	from, err := p.Describe(ctx, src)
+	if err != nil {
+		return Profile{}, err
+	}
+	seed := from.ID
+	if from.ClonedFrom != "" {
+		seed = from.ClonedFrom // src is an unused clone itself
+	}
+	return p.create(ctx, Profile{Name: dst, BrowserType: from.BrowserType, ClonedFrom: seed})
+}
+
+// Rename changes a profile's name. Its data and ID are kept.
+func (p *Profiles) Rename(ctx context.Context, oldName, newName string) error {
+	if newName == "" {
+		return NewBrowserError("Profile name is required")
+	}
+	if err := p.store.RenameProfile(ctx, oldName, newName); err != nil {
+		if errors.Is(err, ErrProfileExists) {
+			return profileError(newName, err)
+		}
+		return profileError(oldName, err)
+	}
+	return nil
+}
+
+// ProfileDeleteOptions tunes Profiles.DeleteWith.
+type ProfileDeleteOptions struct {
+	// Force deletes the profile even while a session holds it, breaking its
+	// lock together with the delete (as BreakLock does). The session keeps
+	// its browser, but workers purge the profile data it is using.
+	Force bool
+}
+
+// Delete removes a profile. Workers purge its data. It fails with
+// ErrProfileLocked while a session holds the profile.
+func (p *Profiles) Delete(ctx context.Context, name string) error {
+	return p.DeleteWith(ctx, name, ProfileDeleteOptions{})
+}
+
+// DeleteWith is like Delete, with options.
+func (p *Profiles) DeleteWith(ctx context.Context, name string, opts ProfileDeleteOptions) error {
+	lock, broken, err := p.store.DeleteProfile(ctx, name, opts.Force)
+	if err != nil {
+		if errors.Is(err, ErrProfileLocked) {
+			return newError(ErrProfileLocked, nil, "Profile '%s' is in use; release its session first or force the delete", name)
+		}
+		return profileError(name, err)
+	}
+	if broken {
+		p.logger.LogAttrs(ctx, slog.LevelWarn, "profile lock broken",
+			slog.String("profile", name),
+			slog.String("holder", lock.Holder))
+	}
+	return nil
+}
+
+func (p *Profiles) create(ctx context.Context, prof Profile) (Profile, error) {
+	if prof.Name == "" {
+		return Profile{}, NewBrowserError("Profile name is required")
+	}
+	if prof.ID == "" {
+		prof.ID = newProfileID()
+	}
+	prof.Created = time.Now().UTC()
+
+	if err := p.store.CreateProfile(ctx, prof); err != nil {
+		return Profile{}, profileError(prof.Name, err)
+	}
+	return prof, nil
+}
+
+// resolve returns the profile a session is about to run with, creating it
+// on first use.
+func (p *Profiles) resolve(ctx context.Context, name, browserType string) (Profile, error) {
+	prof, err := p.store.GetProfile(ctx, name)
+	if errors.Is(err, ErrProfileNotFound) {
+		prof, err = p.Create(ctx, name, browserType)
+		if errors.Is(err, ErrProfileExists) {
+			// Created concurrently by someone else
+			prof, err = p.store.GetProfile(ctx, name)
+		}
+	}
+	if err != nil {
+		return Profile{}, profileError(name, err)
+	}
+	if prof.BrowserType != "" && prof.BrowserType != browserType {
+		return Profile{}, NewBrowserError("Profile '%s' belongs to browser type '%s', not '%s'", name, prof.BrowserType, browserType)
+	}
+	return prof, nil
+}
+
+// touch records that a session now runs with the profile and returns the
+// profile as it was before, so a first use still sees ClonedFrom.
+func (p *Profiles) touch(ctx context.Context, prof Profile, browserType string) Profile {
+	before, err := p.store.TouchProfile(ctx, prof.Name, browserType, time.Now().UTC())
+	if err != nil || before.ID != prof.ID {
+		// Renamed or deleted meanwhile; the session keeps the ID it has
+		return prof
+	}
+	return before
+}
+
+// profileError wraps a ProfileStore error with the profile's name.
+func profileError(name string, err error) error {
+	switch {
+	case errors.Is(err, ErrProfileNotFound):
+		return newError(ErrProfileNotFound, nil, "Profile '%s' not found", name)
+	case errors.Is(err, ErrProfileExists):
+		return newError(ErrProfileExists, nil, "Profile '%s' already exists", name)
+	}
+	return newError(nil, err, "Profile '%s': %v", name, err)
+}
+
+func newProfileID() string {
+	return fmt.Sprintf("user_%s", newHexID()[:16])
+}
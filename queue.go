@@ -0,0 +1,126 @@
+package isoautomate
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// DefaultQueuePoll is how often a waiting Acquire retries even without a
+// release notification (workers free browsers without telling the SDK).
+const DefaultQueuePoll = time.Second
+
+// queueTicketTTL is how long a waiter stays queued without refreshing its
+// ticket; waiters refresh on every poll.
+const queueTicketTTL = 15 * time.Second
+
+// QueueStatus is a waiter's place in an acquire queue.
+type QueueStatus struct {
+	Position      int           // 1 = next browser freed is ours
+	EstimatedWait time.Duration // From the recent pace of the queue; 0 if unknown
+}
+
+// WaitOptions makes Acquire wait for a browser instead of failing at once
+// when none is free (see AcquireOptions.Wait). Waiters are served
+// first-come, first-served, one queue per browser type, but a waiter only
+// holds up the workers its own selection allows: one pinned to a busy
+// worker does not keep the others from browsers free elsewhere.
+type WaitOptions struct {
+	// Timeout bounds the wait; Acquire fails with ErrNoBrowsersAvailable
+	// once it runs out. 0 waits until ctx is done, which fails Acquire with
+	// ctx's error instead.
+	Timeout time.Duration
+
+	// OnQueue, if set, is called with the caller's queue status whenever it
+	// changes.
+	OnQueue func(QueueStatus)
+
+	// PollInterval is how often to retry without a release notification
+	// (default DefaultQueuePoll).
+	PollInterval time.Duration
+}
+
+// acquireQueued joins the wait queue and retries a ticketed reserve each
+// time a browser may have been freed, until it succeeds or the wait ends.
+// It fails with ErrNoBrowsersAvailable if no browser is handed out within
+// wait.Timeout, and with ctx's error if ctx ends first.
+func (c *Client) acquireQueued(ctx context.Context, req AcquireRequest, wait WaitOptions) (Lease, error) {
+	browserType := req.BrowserType
+	parent := ctx
+	if wait.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, wait.Timeout)
+		defer cancel()
+	}
+	poll := wait.PollInterval
+	if poll <= 0 {
+		poll = DefaultQueuePoll
+	}
+
+	// Subscribe before the first attempt so no release is missed.
+	watchCtx, stopWatch := context.WithCancel(ctx)
+	defer stopWatch()
+	released, err := c.transport.WatchReleases(watchCtx, browserType)
+	if err != nil {
+		return Lease{}, newError(nil, err, "Failed to watch for released browsers: %v", err)
+	}
+
+	ticket := newHexID()
+	req.QueueTicket = ticket
+	defer func() {
+		leaveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		_ = c.transport.LeaveQueue(leaveCtx, browserType, ticket)
+		cancel()
+	}()
+
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+
+	var last QueueStatus
+	for {
+		// Refresh the ticket (joining on the first pass), then try our turn.
+		status, err := c.transport.JoinQueue(ctx, req, queueTicketTTL)
+		if err != nil {
+			return Lease{}, c.queueError(parent, ctx, browserType, err)
+		}
+		if status != last {
+			c.logger.LogAttrs(ctx, slog.LevelDebug, "waiting for a browser",
+				slog.String("browser_type", browserType),
+				slog.Int("position", status.Position),
+				slog.Duration("estimated_wait", status.EstimatedWait))
+			if wait.OnQueue != nil {
+				wait.OnQueue(status)
+			}
+		}
+		last = status
+
+		lease, err := c.reserve(ctx, req)
+		if err == nil {
+			return lease, nil
+		}
+		if !errors.Is(err, ErrNoBrowsersAvailable) {
+			return Lease{}, err
+		}
+
+		select {
+		case <-released:
+		case <-ticker.C:
+		case <-ctx.Done():
+			return Lease{}, c.queueError(parent, ctx, browserType, ctx.Err())
+		}
+	}
+}
+
+// queueError reports why a wait ended: ErrNoBrowsersAvailable only if the
+// wait's own Timeout (on ctx) ran out, a plain error if the caller's parent
+// context ended.
+func (c *Client) queueError(parent, ctx context.Context, browserType string, err error) error {
+	if parent.Err() != nil {
+		return newError(nil, parent.Err(), "Waiting for a browser of type '%s' cancelled: %v", browserType, parent.Err())
+	}
+	if ctx.Err() != nil {
+		return newError(ErrNoBrowsersAvailable, ctx.Err(), "No browser of type '%s' became available: %v", browserType, ctx.Err())
+	}
+	return newError(nil, err, "Redis queue error: %v", err)
+}
@@ -0,0 +1,983 @@
+package isoautomate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// cancelMarkerTTL is how long an abandoned task's cancel marker stays in Redis.
+// Workers check the marker before (and while) executing a task.
+const cancelMarkerTTL = 10 * time.Minute
+
+// cancelledResult is pushed onto a result key to wake a BLPOP whose caller
+// has gone away.
+const cancelledResult = `{"status":"cancelled"}`
+
+// Lease bookkeeping: ISOAUTOMATE:leases is a sorted set of
+// "<worker>|<type>|<browser_id>" scored by expiry (Redis TIME, unix ms) and
+// ISOAUTOMATE:lease_tokens maps the same members to the holder's token.
+// All scripts read the clock from Redis so that clients with skewed clocks
+// agree on when a lease expires.
+const luaNowMS = `
+	local t = redis.call('TIME')
+	local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+`
+
+// Acquire wait queues: ISOAUTOMATE:queue:<type> lists waiter tickets in
+// arrival order, and each live waiter refreshes ISOAUTOMATE:waiter:<ticket>,
+// which holds its worker selection (see workerSelection). Tickets whose key
+// has expired belong to crashed waiters and are skipped. The :stats hash
+// tracks how fast the queue moves (ema_ms between served waiters) for the
+// wait estimate.
+const luaQueueAhead = `
+	-- waiters_ahead returns the selections of the live waiters queued
+	-- before ticket, dropping dead ones, and whether ticket is queued.
+	local function waiters_ahead(queue, prefix, ticket)
+		local ahead = {}
+		for _, id in ipairs(redis.call('LRANGE', queue, 0, -1)) do
+			if id == ticket then
+				return ahead, true
+			end
+			local raw = redis.call('GET', prefix .. 'waiter:' .. id)
+			if raw then
+				local ok, sel = pcall(cjson.decode, raw)
+				if not ok or type(sel) ~= 'table' then
+					sel = {} -- No selection: any worker will do
+				end
+				table.insert(ahead, sel)
+			else
+				redis.call('LREM', queue, 1, id)
+			end
+		end
+		return ahead, false
+	end
+`
+
+// luaSelectWorkers filters the workers by a selection (see
+// workerSelection), orders them by the selection's strategy and moves the
+// affinity profile's last worker to the front.
+const luaSelectWorkers = `
+	local function as_set(list)
+		local set = {}
+		if type(list) == 'table' then
+			for _, v in ipairs(list) do
+				set[v] = true
+			end
+		end
+		return set
+	end
+
+	local function sort_by(workers, score, descending)
+		local scores = {}
+		for _, worker in ipairs(workers) do
+			scores[worker] = score(worker)
+		end
+		table.sort(workers, function(a, b)
+			if descending then
+				return scores[a] > scores[b]
+			end
+			return scores[a] < scores[b]
+		end)
+	end
+
+	local function order_workers(workers, prefix, btype, strategy, rr_key)
+		local base = function(worker) return prefix .. worker .. ':' .. btype end
+		if strategy == 'least_busy' then
+			sort_by(workers, function(worker)
+				return redis.call('SCARD', base(worker) .. ':busy')
+			end, false)
+		elseif strategy == 'round_robin' and #workers > 0 then
+			table.sort(workers)
+			local start = (redis.call('INCR', rr_key) - 1) % #workers
+			local rotated = {}
+			for i = 1, #workers do
+				rotated[i] = workers[(start + i - 1) % #workers + 1]
+			end
+			return rotated
+		elseif strategy == 'weighted' then
+			-- Weighted random order: key = u^(1/w), largest first
+			sort_by(workers, function(worker)
+				local w = tonumber(redis.call('HGET', prefix .. worker .. ':labels', 'capacity'))
+				if not w then
+					w = redis.call('SCARD', base(worker) .. ':free') + redis.call('SCARD', base(worker) .. ':busy')
+				end
+				if w <= 0 then
+					return -1
+				end
+				return math.random() ^ (1 / w)
+			end, true)
+		end
+		return workers
+	end
+
+	local function filter_workers(workers, prefix, sel)
+		local allow = as_set(sel.workers)
+		local restricted = type(sel.workers) == 'table' and #sel.workers > 0
+		local deny = as_set(sel.exclude)
+
+		local out = {}
+		for _, worker in ipairs(workers) do
+			local ok = (not restricted or allow[worker]) and not deny[worker]
+			if ok and type(sel.labels) == 'table' then
+				for k, v in pairs(sel.labels) do
+					if redis.call('HGET', prefix .. worker .. ':labels', k) ~= v then
+						ok = false
+						break
+					end
+				end
+			end
+			if ok then
+				table.insert(out, worker)
+			end
+		end
+		return out
+	end
+
+	local function select_workers(workers, prefix, btype, sel, affinity_key, rr_key)
+		local out = order_workers(filter_workers(workers, prefix, sel), prefix, btype, sel.strategy, rr_key)
+
+		if type(sel.affinity) == 'string' then
+			local preferred = redis.call('HGET', affinity_key, sel.affinity)
+			for i, worker in ipairs(out) do
+				if worker == preferred then
+					table.remove(out, i)
+					table.insert(out, 1, worker)
+					break
+				end
+			end
+		end
+		return out
+	end
+`
+
+// acquireScript pops a free browser from the first worker (in strategy
+// order) that has one, marks it busy and leases it. (Exact copy of Python
+// logic, plus the lease, the wait queue and worker selection)
+// KEYS[1] = workers set, KEYS[2] = leases zset, KEYS[3] = lease tokens hash,
+// KEYS[4] = wait queue, KEYS[5] = queue stats hash, KEYS[6] = profile workers hash,
+// KEYS[7] = round-robin counter, KEYS[8] = browser types set
+// ARGV[1] = key prefix, ARGV[2] = browser type, ARGV[3] = lease token, ARGV[4] = lease TTL (ms),
+// ARGV[5] = queue ticket (empty if not waiting), ARGV[6] = worker selection (JSON),
+// ARGV[7] = profile ID (empty without a profile)
+var acquireScript = redis.NewScript(luaNowMS + luaQueueAhead + luaSelectWorkers + `
+	redis.call('SADD', KEYS[8], ARGV[2])
+
+	local workers = redis.call('SMEMBERS', KEYS[1])
+	for i = #workers, 2, -1 do
+		local j = math.random(i)
+		workers[i], workers[j] = workers[j], workers[i]
+	end
+	workers = select_workers(workers, ARGV[1], ARGV[2], cjson.decode(ARGV[6]), KEYS[6], KEYS[7])
+
+	-- Waiters are served first-come, first-served: a worker that a waiter
+	-- ahead of us may use is left to it. Workers outside every such
+	-- waiter's selection are fair game.
+	local ahead, queued = waiters_ahead(KEYS[4], ARGV[1], ARGV[5])
+	local claimed = {}
+	for _, sel in ipairs(ahead) do
+		for _, worker in ipairs(filter_workers(workers, ARGV[1], sel)) do
+			claimed[worker] = true
+		end
+	end
+
+	for _, worker in ipairs(workers) do
+		local free_key = ARGV[1] .. worker .. ':' .. ARGV[2] .. ':free'
+		local bid = nil
+		if not claimed[worker] then
+			bid = redis.call('SPOP', free_key)
+		end
+		if bid then
+			local busy_key = ARGV[1] .. worker .. ':' .. ARGV[2] .. ':busy'
+			redis.call('SADD', busy_key, bid)
+			local member = worker .. '|' .. ARGV[2] .. '|' .. bid
+			local expires = now + tonumber(ARGV[4])
+			redis.call('ZADD', KEYS[2], expires, member)
+			redis.call('HSET', KEYS[3], member, ARGV[3])
+			if ARGV[7] ~= '' then
+				redis.call('HSET', KEYS[6], ARGV[7], worker)
+			end
+
+			if queued then
+				redis.call('LREM', KEYS[4], 1, ARGV[5])
+				redis.call('DEL', ARGV[1] .. 'waiter:' .. ARGV[5])
+				-- Measure the pace only while waiters are queued back to back
+				local last = tonumber(redis.call('HGET', KEYS[5], 'last_ms'))
+				if last then
+					local ema = tonumber(redis.call('HGET', KEYS[5], 'ema_ms')) or (now - last)
+					redis.call('HSET', KEYS[5], 'ema_ms', math.floor(ema * 0.7 + (now - last) * 0.3))
+				end
+				if redis.call('LLEN', KEYS[4]) > 0 then
+					redis.call('HSET', KEYS[5], 'last_ms', now)
+				else
+					redis.call('HDEL', KEYS[5], 'last_ms')
+				end
+			end
+			return {worker, bid, tostring(expires)}
+		end
+	end
+	return nil
+`)
+
+// joinQueueScript queues a ticket (or refreshes it) with its waiter's
+// worker selection and returns its live position and the queue's pace.
+// KEYS[1] = wait queue, KEYS[2] = queue stats hash
+// ARGV[1] = key prefix, ARGV[2] = ticket, ARGV[3] = ticket TTL (ms),
+// ARGV[4] = worker selection (JSON)
+var joinQueueScript = redis.NewScript(`
+	redis.call('SET', ARGV[1] .. 'waiter:' .. ARGV[2], ARGV[4], 'PX', ARGV[3])
+
+	local pos = 0
+	local found = false
+	for _, ticket in ipairs(redis.call('LRANGE', KEYS[1], 0, -1)) do
+		if redis.call('EXISTS', ARGV[1] .. 'waiter:' .. ticket) == 0 then
+			redis.call('LREM', KEYS[1], 1, ticket)
+		else
+			pos = pos + 1
+			if ticket == ARGV[2] then
+				found = true
+				break
+			end
+		end
+	end
+	if not found then
+		redis.call('RPUSH', KEYS[1], ARGV[2])
+		pos = pos + 1
+	end
+	return {pos, tonumber(redis.call('HGET', KEYS[2], 'ema_ms')) or 0}
+`)
+
+// leaveQueueScript removes a ticket and wakes the waiters behind it.
+// KEYS[1] = wait queue
+// ARGV[1] = key prefix, ARGV[2] = ticket, ARGV[3] = released channel
+var leaveQueueScript = redis.NewScript(`
+	redis.call('DEL', ARGV[1] .. 'waiter:' .. ARGV[2])
+	if redis.call('LREM', KEYS[1], 1, ARGV[2]) > 0 then
+		redis.call('PUBLISH', ARGV[3], ARGV[2])
+	end
+	return 0
+`)
+
+// renewScript pushes a lease's expiry forward if the caller still holds it.
+// KEYS[1] = leases zset, KEYS[2] = lease tokens hash
+// ARGV[1] = member, ARGV[2] = token, ARGV[3] = lease TTL (ms)
+var renewScript = redis.NewScript(luaNowMS + `
+	if redis.call('HGET', KEYS[2], ARGV[1]) ~= ARGV[2] then
+		return -1
+	end
+	local expires = now + tonumber(ARGV[3])
+	redis.call('ZADD', KEYS[1], 'XX', expires, ARGV[1])
+	return expires
+`)
+
+// dropLeaseScript forgets a lease if the caller still holds it.
+// KEYS[1] = leases zset, KEYS[2] = lease tokens hash
+// ARGV[1] = member, ARGV[2] = token
+var dropLeaseScript = redis.NewScript(`
+	if redis.call('HGET', KEYS[2], ARGV[1]) == ARGV[2] then
+		redis.call('ZREM', KEYS[1], ARGV[1])
+		redis.call('HDEL', KEYS[2], ARGV[1])
+		return 1
+	end
+	return 0
+`)
+
+// luaFreeBrowser moves a browser from its worker's busy set back to the
+// free set and wakes the type's waiters. It is a no-op if the browser is not
+// busy.
+const luaFreeBrowser = `
+	local function free_browser(prefix, worker, btype, bid)
+		local base = prefix .. worker .. ':' .. btype
+		if redis.call('SREM', base .. ':busy', bid) == 1 then
+			redis.call('SADD', base .. ':free', bid)
+			redis.call('PUBLISH', prefix .. 'released:' .. btype, bid)
+			return 1
+		end
+		return 0
+	end
+`
+
+// releaseScript moves a browser from the busy set back to the free set and
+// drops its lease. The move is a no-op if the browser is not busy.
+// KEYS[1] = leases zset, KEYS[2] = lease tokens hash
+// ARGV[1] = key prefix, ARGV[2] = worker, ARGV[3] = browser type, ARGV[4] = browser ID
+var releaseScript = redis.NewScript(luaFreeBrowser + `
+	local member = ARGV[2] .. '|' .. ARGV[3] .. '|' .. ARGV[4]
+	redis.call('ZREM', KEYS[1], member)
+	redis.call('HDEL', KEYS[2], member)
+	return free_browser(ARGV[1], ARGV[2], ARGV[3], ARGV[4])
+`)
+
+// releaseLeaseScript is releaseScript for a caller that must still hold the
+// lease; it returns -1 if the lease was lost.
+// KEYS[1] = leases zset, KEYS[2] = lease tokens hash
+// ARGV[1] = key prefix, ARGV[2] = worker, ARGV[3] = browser type, ARGV[4] = browser ID,
+// ARGV[5] = token
+var releaseLeaseScript = redis.NewScript(luaFreeBrowser + `
+	local member = ARGV[2] .. '|' .. ARGV[3] .. '|' .. ARGV[4]
+	if redis.call('HGET', KEYS[2], member) ~= ARGV[5] then
+		return -1
+	end
+	redis.call('ZREM', KEYS[1], member)
+	redis.call('HDEL', KEYS[2], member)
+	return free_browser(ARGV[1], ARGV[2], ARGV[3], ARGV[4])
+`)
+
+// reapScript takes over every expired lease whose browser is still busy:
+// the lease passes to the reaper's token for the hold time, which keeps the
+// browser out of reach until the reaper has queued its reset and released
+// it (see Client.ReapExpiredLeases). Expired leases of browsers that are no
+// longer busy are dropped.
+// KEYS[1] = leases zset, KEYS[2] = lease tokens hash
+// ARGV[1] = key prefix, ARGV[2] = reaper token, ARGV[3] = hold time (ms)
+var reapScript = redis.NewScript(luaNowMS + `
+	local expired = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', now, 'WITHSCORES')
+	local reaped = {}
+	for i = 1, #expired, 2 do
+		local member = expired[i]
+		local worker, btype, bid = string.match(member, '^([^|]*)|([^|]*)|(.*)$')
+		if worker and redis.call('SISMEMBER', ARGV[1] .. worker .. ':' .. btype .. ':busy', bid) == 1 then
+			redis.call('ZADD', KEYS[1], now + tonumber(ARGV[3]), member)
+			redis.call('HSET', KEYS[2], member, ARGV[2])
+			table.insert(reaped, member)
+			table.insert(reaped, expired[i + 1])
+		else
+			redis.call('ZREM', KEYS[1], member)
+			redis.call('HDEL', KEYS[2], member)
+		end
+	end
+	return reaped
+`)
+
+// browserCountsScript sizes the free and busy sets of every registered
+// worker for every known browser type, skipping pairs with neither.
+// KEYS[1] = workers set, KEYS[2] = browser types set
+// ARGV[1] = key prefix
+var browserCountsScript = redis.NewScript(`
+	local types = redis.call('SMEMBERS', KEYS[2])
+	local counts = {}
+	for _, worker in ipairs(redis.call('SMEMBERS', KEYS[1])) do
+		for _, btype in ipairs(types) do
+			local base = ARGV[1] .. worker .. ':' .. btype
+			local free = redis.call('SCARD', base .. ':free')
+			local busy = redis.call('SCARD', base .. ':busy')
+			if free + busy > 0 then
+				table.insert(counts, worker)
+				table.insert(counts, btype)
+				table.insert(counts, free)
+				table.insert(counts, busy)
+			end
+		end
+	end
+	return counts
+`)
+
+// workerSelection is the JSON form of an AcquireRequest's worker
+// selection, as read by luaSelectWorkers.
+type workerSelection struct {
+	Workers  []string          `json:"workers,omitempty"`
+	Exclude  []string          `json:"exclude,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Affinity string            `json:"affinity,omitempty"`
+	Strategy Strategy          `json:"strategy,omitempty"`
+}
+
+// renameProfileScript moves a profile to a new name.
+// KEYS[1] = profiles hash
+// ARGV[1] = old name, ARGV[2] = new name
+var renameProfileScript = redis.NewScript(`
+	local raw = redis.call('HGET', KEYS[1], ARGV[1])
+	if not raw then
+		return 0
+	end
+	if redis.call('HEXISTS', KEYS[1], ARGV[2]) == 1 then
+		return -1
+	end
+	local p = cjson.decode(raw)
+	p.name = ARGV[2]
+	redis.call('HSET', KEYS[1], ARGV[2], cjson.encode(p))
+	redis.call('HDEL', KEYS[1], ARGV[1])
+	return 1
+`)
+
+// deleteProfileScript removes a profile and queues its ID for purging,
+// unless its lock is held. Forced, it breaks the lock and returns it.
+// KEYS[1] = profiles hash, KEYS[2] = deleted IDs set, KEYS[3] = profile workers hash
+// ARGV[1] = name, ARGV[2] = profile lock key prefix, ARGV[3] = '1' to force
+var deleteProfileScript = redis.NewScript(luaNowMS + `
+	local raw = redis.call('HGET', KEYS[1], ARGV[1])
+	if not raw then
+		return {'0'}
+	end
+	local p = cjson.decode(raw)
+	local lock_key = ARGV[2] .. p.id
+	local lock = redis.call('GET', lock_key)
+	local expires = 0
+	if lock then
+		if ARGV[3] ~= '1' then
+			return {'-1'}
+		end
+		expires = now + redis.call('PTTL', lock_key)
+		redis.call('DEL', lock_key)
+	end
+	redis.call('HDEL', KEYS[1], ARGV[1])
+	redis.call('SADD', KEYS[2], p.id)
+	redis.call('HDEL', KEYS[3], p.id)
+	if lock then
+		return {'1', lock, tostring(expires)}
+	end
+	return {'1'}
+`)
+
+// touchProfileScript records a use of a profile and returns it as it was.
+// KEYS[1] = profiles hash
+// ARGV[1] = name, ARGV[2] = browser type, ARGV[3] = time (RFC 3339)
+var touchProfileScript = redis.NewScript(`
+	local raw = redis.call('HGET', KEYS[1], ARGV[1])
+	if not raw then
+		return false
+	end
+	local p = cjson.decode(raw)
+	p.last_used = ARGV[3]
+	if p.browser_type == nil or p.browser_type == '' then
+		p.browser_type = ARGV[2]
+	end
+	p.cloned_from = nil
+	redis.call('HSET', KEYS[1], ARGV[1], cjson.encode(p))
+	return raw
+`)
+
+// Profile locks: ISOAUTOMATE:profile_lock:<id> holds the holder's
+// ProfileLock as JSON and expires with the lock.
+
+// lockProfileScript takes a profile lock if it is free, and otherwise
+// returns the current one.
+// KEYS[1] = lock key
+// ARGV[1] = lock JSON, ARGV[2] = TTL (ms)
+var lockProfileScript = redis.NewScript(luaNowMS + `
+	local cur = redis.call('GET', KEYS[1])
+	if cur then
+		return {'0', cur, tostring(now + redis.call('PTTL', KEYS[1]))}
+	end
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+	return {'1', ARGV[1], tostring(now + tonumber(ARGV[2]))}
+`)
+
+// renewProfileLockScript extends a profile lock if the caller still holds it.
+// KEYS[1] = lock key
+// ARGV[1] = token, ARGV[2] = TTL (ms)
+var renewProfileLockScript = redis.NewScript(luaNowMS + `
+	local cur = redis.call('GET', KEYS[1])
+	if not cur or cjson.decode(cur).token ~= ARGV[1] then
+		return -1
+	end
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+	return now + tonumber(ARGV[2])
+`)
+
+// unlockProfileScript deletes a profile lock if the caller still holds it.
+// KEYS[1] = lock key
+// ARGV[1] = token
+var unlockProfileScript = redis.NewScript(`
+	local cur = redis.call('GET', KEYS[1])
+	if cur and cjson.decode(cur).token == ARGV[1] then
+		redis.call('DEL', KEYS[1])
+		return 1
+	end
+	return 0
+`)
+
+// breakProfileLockScript deletes a profile lock and returns it.
+// KEYS[1] = lock key
+var breakProfileLockScript = redis.NewScript(luaNowMS + `
+	local cur = redis.call('GET', KEYS[1])
+	if not cur then
+		return false
+	end
+	local expires = now + redis.call('PTTL', KEYS[1])
+	redis.call('DEL', KEYS[1])
+	return {cur, tostring(expires)}
+`)
+
+// RedisTransport is the Transport used by isoFleet: tasks travel through
+// Redis lists and browser accounting lives in per-worker Redis sets.
+type RedisTransport struct {
+	rdb  redis.UniversalClient
+	keys keyspace
+}
+
+var _ Transport = (*RedisTransport)(nil)
+
+// NewRedisTransport wraps an existing go-redis client: a single node, a
+// Sentinel failover client or a Cluster client. On a *redis.ClusterClient
+// every key is hash-tagged into one slot (see RedisPrefix).
+func NewRedisTransport(rdb redis.UniversalClient) *RedisTransport {
+	t, _ := NewRedisTransportWithNamespace(rdb, DefaultNamespace)
+	return t
+}
+
+// NewRedisTransportWithNamespace is like NewRedisTransport for a fleet whose
+// keys live under another namespace (see Config.Namespace).
+func NewRedisTransportWithNamespace(rdb redis.UniversalClient, namespace string) (*RedisTransport, error) {
+	if !validNamespace(namespace) {
+		return nil, NewBrowserError("Invalid namespace '%s'", namespace)
+	}
+	_, cluster := rdb.(*redis.ClusterClient)
+	return &RedisTransport{rdb: rdb, keys: newKeyspace(namespace, cluster)}, nil
+}
+
+// Redis returns the underlying go-redis client.
+func (t *RedisTransport) Redis() redis.UniversalClient {
+	return t.rdb
+}
+
+// Enqueue pushes a task onto ISOAUTOMATE:<worker>:tasks.
+func (t *RedisTransport) Enqueue(ctx context.Context, worker string, task []byte) error {
+	return t.rdb.RPush(ctx, t.keys.tasks(worker), task).Err()
+}
+
+// Await blocks on BLPOP for resultKey until a result arrives, timeout
+// elapses or ctx is done.
+//
+// go-redis only interrupts a blocking read on a deadline, not on cancellation,
+// so the BLPOP runs in its own goroutine. When ctx is cancelled we return at
+// once and push a wake-up marker onto resultKey so the BLPOP finishes and its
+// pooled connection is handed back. The key gets a short expiry in case the
+// BLPOP had already returned.
+func (t *RedisTransport) Await(ctx context.Context, resultKey string, timeout time.Duration) ([]byte, error) {
+	type popResult struct {
+		vals []string
+		err  error
+	}
+	done := make(chan popResult, 1)
+
+	go func() {
+		popCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+		defer cancel()
+
+		vals, err := t.rdb.BLPop(popCtx, timeout, resultKey).Result()
+		done <- popResult{vals: vals, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			if r.err == redis.Nil || errors.Is(r.err, context.DeadlineExceeded) {
+				return nil, ErrTimeout
+			}
+			return nil, r.err
+		}
+		if len(r.vals) < 2 {
+			return nil, ErrInvalidResponse
+		}
+		return []byte(r.vals[1]), nil
+	case <-ctx.Done():
+		wakeCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		pipe := t.rdb.TxPipeline()
+		pipe.LPush(wakeCtx, resultKey, cancelledResult)
+		pipe.Expire(wakeCtx, resultKey, time.Minute)
+		_, _ = pipe.Exec(wakeCtx)
+		return nil, ctx.Err()
+	}
+}
+
+// CancelTask sets ISOAUTOMATE:cancel:<taskID> so the worker skips (or stops)
+// the task.
+func (t *RedisTransport) CancelTask(ctx context.Context, taskID string) error {
+	return t.rdb.Set(ctx, t.keys.cancel(taskID), "1", cancelMarkerTTL).Err()
+}
+
+// selection encodes req's worker selection for the Lua scripts.
+func (req AcquireRequest) selection() ([]byte, error) {
+	return json.Marshal(workerSelection{
+		Workers:  req.Workers,
+		Exclude:  req.Exclude,
+		Labels:   req.Labels,
+		Affinity: req.Affinity,
+		Strategy: req.Strategy,
+	})
+}
+
+// AcquireBrowser runs the acquire Lua script.
+func (t *RedisTransport) AcquireBrowser(ctx context.Context, req AcquireRequest) (Lease, error) {
+	selection, err := req.selection()
+	if err != nil {
+		return Lease{}, err
+	}
+
+	keys := []string{t.keys.workers(), t.keys.leases(), t.keys.leaseTokens(),
+		t.keys.queue(req.BrowserType), t.keys.queueStats(req.BrowserType), t.keys.profileWorkers(),
+		t.keys.roundRobin(req.BrowserType), t.keys.browserTypes()}
+	result, err := acquireScript.Run(ctx, t.rdb, keys,
+		t.keys.prefix, req.BrowserType, req.LeaseToken, req.LeaseTTL.Milliseconds(), req.QueueTicket,
+		selection, req.ProfileID).Result()
+	if err == redis.Nil {
+		return Lease{}, ErrNoBrowsersAvailable
+	}
+	if err != nil {
+		return Lease{}, err
+	}
+
+	// Parse Result ([worker_name, browser_id, expires_ms])
+	resSlice, ok := result.([]interface{})
+	if !ok || len(resSlice) < 3 {
+		return Lease{}, ErrInvalidResponse
+	}
+	workerName, _ := resSlice[0].(string)
+	bid, _ := resSlice[1].(string)
+	expires, _ := resSlice[2].(string)
+	return Lease{
+		Worker:      workerName,
+		BrowserType: req.BrowserType,
+		BrowserID:   bid,
+		Token:       req.LeaseToken,
+		Expires:     parseUnixMS(expires),
+	}, nil
+}
+
+// ReleaseBrowser moves a busy browser back to its worker's free set and
+// drops its lease.
+func (t *RedisTransport) ReleaseBrowser(ctx context.Context, worker, browserType, browserID string) error {
+	return releaseScript.Run(ctx, t.rdb, []string{t.keys.leases(), t.keys.leaseTokens()},
+		t.keys.prefix, worker, browserType, browserID).Err()
+}
+
+// ReleaseLease runs the release script for a lease the caller holds.
+func (t *RedisTransport) ReleaseLease(ctx context.Context, lease Lease) error {
+	n, err := releaseLeaseScript.Run(ctx, t.rdb, []string{t.keys.leases(), t.keys.leaseTokens()},
+		t.keys.prefix, lease.Worker, lease.BrowserType, lease.BrowserID, lease.Token).Int()
+	if err != nil {
+		return err
+	}
+	if n < 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// BrowserBusy checks ISOAUTOMATE:<worker>:<type>:busy.
+func (t *RedisTransport) BrowserBusy(ctx context.Context, worker, browserType, browserID string) (bool, error) {
+	return t.rdb.SIsMember(ctx, t.keys.browsers(worker, browserType, "busy"), browserID).Result()
+}
+
+// BrowserCounts runs the browser counts Lua script.
+func (t *RedisTransport) BrowserCounts(ctx context.Context) ([]BrowserCount, error) {
+	result, err := browserCountsScript.Run(ctx, t.rdb, []string{t.keys.workers(), t.keys.browserTypes()}, t.keys.prefix).Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	// Flat [worker, type, free, busy, ...]
+	counts := make([]BrowserCount, 0, len(result)/4)
+	for i := 0; i+3 < len(result); i += 4 {
+		worker, _ := result[i].(string)
+		browserType, _ := result[i+1].(string)
+		free, _ := result[i+2].(int64)
+		busy, _ := result[i+3].(int64)
+		counts = append(counts, BrowserCount{Worker: worker, BrowserType: browserType, Free: int(free), Busy: int(busy)})
+	}
+	sortBrowserCounts(counts)
+	return counts, nil
+}
+
+// RenewLease extends a lease the caller still holds.
+func (t *RedisTransport) RenewLease(ctx context.Context, lease Lease, ttl time.Duration) (time.Time, error) {
+	expires, err := renewScript.Run(ctx, t.rdb, []string{t.keys.leases(), t.keys.leaseTokens()},
+		lease.member(), lease.Token, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if expires < 0 {
+		return time.Time{}, ErrLeaseLost
+	}
+	return time.UnixMilli(expires), nil
+}
+
+// DropLease forgets a lease without touching the browser sets.
+func (t *RedisTransport) DropLease(ctx context.Context, lease Lease) error {
+	return dropLeaseScript.Run(ctx, t.rdb, []string{t.keys.leases(), t.keys.leaseTokens()},
+		lease.member(), lease.Token).Err()
+}
+
+// ReapExpiredLeases runs the reap Lua script.
+func (t *RedisTransport) ReapExpiredLeases(ctx context.Context, token string, hold time.Duration) ([]Lease, error) {
+	result, err := reapScript.Run(ctx, t.rdb, []string{t.keys.leases(), t.keys.leaseTokens()},
+		t.keys.prefix, token, hold.Milliseconds()).StringSlice()
+	if err != nil {
+		return nil, err
+	}
+
+	leases := make([]Lease, 0, len(result)/2)
+	for i := 0; i+1 < len(result); i += 2 {
+		lease, ok := parseLeaseMember(result[i])
+		if !ok {
+			continue
+		}
+		lease.Expires = parseUnixMS(result[i+1])
+		leases = append(leases, lease)
+	}
+	return leases, nil
+}
+
+// JoinQueue queues the request's ticket on ISOAUTOMATE:queue:<type>, or
+// refreshes it.
+func (t *RedisTransport) JoinQueue(ctx context.Context, req AcquireRequest, ttl time.Duration) (QueueStatus, error) {
+	selection, err := req.selection()
+	if err != nil {
+		return QueueStatus{}, err
+	}
+	vals, err := joinQueueScript.Run(ctx, t.rdb, []string{t.keys.queue(req.BrowserType), t.keys.queueStats(req.BrowserType)},
+		t.keys.prefix, req.QueueTicket, ttl.Milliseconds(), selection).Int64Slice()
+	if err != nil {
+		return QueueStatus{}, err
+	}
+	if len(vals) < 2 {
+		return QueueStatus{}, ErrInvalidResponse
+	}
+	// The pace is the gap between two served waiters; everyone ahead of
+	// us, and then we, need one.
+	return QueueStatus{
+		Position:      int(vals[0]),
+		EstimatedWait: time.Duration(vals[0]*vals[1]) * time.Millisecond,
+	}, nil
+}
+
+// LeaveQueue removes ticket from the wait queue.
+func (t *RedisTransport) LeaveQueue(ctx context.Context, browserType, ticket string) error {
+	return leaveQueueScript.Run(ctx, t.rdb, []string{t.keys.queue(browserType)},
+		t.keys.prefix, ticket, t.keys.released(browserType)).Err()
+}
+
+// WatchReleases subscribes to ISOAUTOMATE:released:<type>.
+func (t *RedisTransport) WatchReleases(ctx context.Context, browserType string) (<-chan struct{}, error) {
+	sub := t.rdb.Subscribe(ctx, t.keys.released(browserType))
+	// Wait for the subscription to be confirmed so no release is missed.
+	if _, err := sub.Receive(ctx); err != nil {
+		_ = sub.Close()
+		return nil, err
+	}
+
+	out := make(chan struct{}, 1)
+	go func() {
+		defer sub.Close()
+
+		msgs := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-msgs:
+				if !ok {
+					return
+				}
+				select {
+				case out <- struct{}{}:
+				default: // A signal is already pending
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+// NotifyReleased publishes on ISOAUTOMATE:released:<type>.
+func (t *RedisTransport) NotifyReleased(ctx context.Context, browserType string) error {
+	return t.rdb.Publish(ctx, t.keys.released(browserType), "").Err()
+}
+
+// CreateProfile adds a profile to the ISOAUTOMATE:profiles hash.
+func (t *RedisTransport) CreateProfile(ctx context.Context, p Profile) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	created, err := t.rdb.HSetNX(ctx, t.keys.profiles(), p.Name, data).Result()
+	if err != nil {
+		return err
+	}
+	if !created {
+		return ErrProfileExists
+	}
+	return nil
+}
+
+// GetProfile reads a profile from the ISOAUTOMATE:profiles hash.
+func (t *RedisTransport) GetProfile(ctx context.Context, name string) (Profile, error) {
+	raw, err := t.rdb.HGet(ctx, t.keys.profiles(), name).Result()
+	if err == redis.Nil {
+		return Profile{}, ErrProfileNotFound
+	}
+	if err != nil {
+		return Profile{}, err
+	}
+	return decodeProfile(raw)
+}
+
+// ListProfiles reads the whole ISOAUTOMATE:profiles hash.
+func (t *RedisTransport) ListProfiles(ctx context.Context) ([]Profile, error) {
+	all, err := t.rdb.HGetAll(ctx, t.keys.profiles()).Result()
+	if err != nil {
+		return nil, err
+	}
+	profiles := make([]Profile, 0, len(all))
+	for _, raw := range all {
+		p, err := decodeProfile(raw)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles, nil
+}
+
+// RenameProfile runs the rename Lua script.
+func (t *RedisTransport) RenameProfile(ctx context.Context, oldName, newName string) error {
+	res, err := renameProfileScript.Run(ctx, t.rdb, []string{t.keys.profiles()}, oldName, newName).Int()
+	if err != nil {
+		return err
+	}
+	switch res {
+	case 0:
+		return ErrProfileNotFound
+	case -1:
+		return ErrProfileExists
+	}
+	return nil
+}
+
+// DeleteProfile runs the delete Lua script.
+func (t *RedisTransport) DeleteProfile(ctx context.Context, name string, force bool) (ProfileLock, bool, error) {
+	forced := "0"
+	if force {
+		forced = "1"
+	}
+	vals, err := deleteProfileScript.Run(ctx, t.rdb, []string{t.keys.profiles(), t.keys.deletedProfiles(), t.keys.profileWorkers()},
+		name, t.keys.profileLock(""), forced).StringSlice()
+	if err != nil {
+		return ProfileLock{}, false, err
+	}
+	switch {
+	case len(vals) == 0:
+		return ProfileLock{}, false, ErrInvalidResponse
+	case vals[0] == "0":
+		return ProfileLock{}, false, ErrProfileNotFound
+	case vals[0] == "-1":
+		return ProfileLock{}, false, ErrProfileLocked
+	case len(vals) < 3:
+		return ProfileLock{}, false, nil
+	}
+	lock, err := decodeProfileLock(vals[1], vals[2])
+	return lock, err == nil, err
+}
+
+// TouchProfile runs the touch Lua script.
+func (t *RedisTransport) TouchProfile(ctx context.Context, name, browserType string, at time.Time) (Profile, error) {
+	raw, err := touchProfileScript.Run(ctx, t.rdb, []string{t.keys.profiles()},
+		name, browserType, at.Format(time.RFC3339Nano)).Text()
+	if err == redis.Nil {
+		return Profile{}, ErrProfileNotFound
+	}
+	if err != nil {
+		return Profile{}, err
+	}
+	return decodeProfile(raw)
+}
+
+// LockProfile runs the lock Lua script.
+func (t *RedisTransport) LockProfile(ctx context.Context, lock ProfileLock, ttl time.Duration) (ProfileLock, error) {
+	data, err := json.Marshal(lock)
+	if err != nil {
+		return ProfileLock{}, err
+	}
+	vals, err := lockProfileScript.Run(ctx, t.rdb, []string{t.keys.profileLock(lock.ProfileID)},
+		data, ttl.Milliseconds()).StringSlice()
+	if err != nil {
+		return ProfileLock{}, err
+	}
+	if len(vals) < 3 {
+		return ProfileLock{}, ErrInvalidResponse
+	}
+	held, err := decodeProfileLock(vals[1], vals[2])
+	if err != nil {
+		return ProfileLock{}, err
+	}
+	if vals[0] != "1" {
+		return held, ErrProfileLocked
+	}
+	return held, nil
+}
+
+// RenewProfileLock extends a lock the caller still holds.
+func (t *RedisTransport) RenewProfileLock(ctx context.Context, lock ProfileLock, ttl time.Duration) (time.Time, error) {
+	expires, err := renewProfileLockScript.Run(ctx, t.rdb, []string{t.keys.profileLock(lock.ProfileID)},
+		lock.Token, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if expires < 0 {
+		return time.Time{}, ErrLeaseLost
+	}
+	return time.UnixMilli(expires), nil
+}
+
+// UnlockProfile deletes a lock the caller still holds.
+func (t *RedisTransport) UnlockProfile(ctx context.Context, lock ProfileLock) error {
+	return unlockProfileScript.Run(ctx, t.rdb, []string{t.keys.profileLock(lock.ProfileID)}, lock.Token).Err()
+}
+
+// BreakProfileLock deletes a profile's lock whoever holds it.
+func (t *RedisTransport) BreakProfileLock(ctx context.Context, profileID string) (ProfileLock, bool, error) {
+	vals, err := breakProfileLockScript.Run(ctx, t.rdb, []string{t.keys.profileLock(profileID)}).StringSlice()
+	if err == redis.Nil {
+		return ProfileLock{}, false, nil
+	}
+	if err != nil {
+		return ProfileLock{}, false, err
+	}
+	if len(vals) < 2 {
+		return ProfileLock{}, false, ErrInvalidResponse
+	}
+	lock, err := decodeProfileLock(vals[0], vals[1])
+	return lock, err == nil, err
+}
+
+func decodeProfileLock(raw, expires string) (ProfileLock, error) {
+	var lock ProfileLock
+	if err := json.Unmarshal([]byte(raw), &lock); err != nil {
+		return ProfileLock{}, ErrInvalidResponse
+	}
+	lock.Expires = parseUnixMS(expires)
+	return lock, nil
+}
+
+func decodeProfile(raw string) (Profile, error) {
+	var p Profile
+	if err := json.Unmarshal([]byte(raw), &p); err != nil {
+		return Profile{}, ErrInvalidResponse
+	}
+	return p, nil
+}
+
+// Ping checks the Redis connection.
+func (t *RedisTransport) Ping(ctx context.Context) error {
+	return t.rdb.Ping(ctx).Err()
+}
+
+// Close closes the Redis connection pool.
+func (t *RedisTransport) Close() error {
+	return t.rdb.Close()
+}
+
+// parseUnixMS turns a Lua-formatted millisecond timestamp into a time.Time.
+func parseUnixMS(s string) time.Time {
+	ms, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.UnixMilli(int64(ms))
+}
@@ -0,0 +1,152 @@
+package isoautomate
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RetryPolicy controls how task pushes and result waits are retried after a
+// transient failure. Acquires are not retried: a reply lost after the
+// acquire script ran would leave its browser reserved until the lease runs
+// out. Zero fields take their default.
+type RetryPolicy struct {
+	MaxAttempts    int           // Total attempts including the first one (default 4; 1 disables retries)
+	InitialBackoff time.Duration // Wait before the first retry (default 200ms)
+	MaxBackoff     time.Duration // Upper bound for a single wait (default 5s)
+	Multiplier     float64       // Backoff growth per retry (default 2)
+	Jitter         *float64      // Random spread as a fraction of the backoff, 0-1 (default 0.2; Ptr(0.0) disables it)
+	MaxElapsed     time.Duration // Give up once this much time has passed since the first attempt (0 = no limit)
+}
+
+// DefaultRetryPolicy matches the Python SDK's @redis_retry decorator:
+// 3 retries with 0.2s, 0.4s, 0.8s backoff, plus a little jitter.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: 200 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     2,
+		Jitter:         Ptr(0.2),
+	}
+}
+
+// withDefaults fills zero fields from DefaultRetryPolicy.
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	d := DefaultRetryPolicy()
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = d.MaxAttempts
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = d.InitialBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = d.MaxBackoff
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = d.Multiplier
+	}
+	switch {
+	case p.Jitter == nil:
+		p.Jitter = d.Jitter
+	case *p.Jitter < 0:
+		p.Jitter = Ptr(0.0)
+	case *p.Jitter > 1:
+		p.Jitter = Ptr(1.0)
+	}
+	return p
+}
+
+// backoff returns the wait before retry number n (1-based).
+func (p RetryPolicy) backoff(n int) time.Duration {
+	wait := float64(p.InitialBackoff)
+	for i := 1; i < n; i++ {
+		wait *= p.Multiplier
+		if wait >= float64(p.MaxBackoff) {
+			wait = float64(p.MaxBackoff)
+			break
+		}
+	}
+	// Spread evenly over [wait*(1-jitter), wait*(1+jitter)]
+	jitter := *p.Jitter
+	wait *= 1 - jitter + 2*jitter*rand.Float64()
+	return min(time.Duration(wait), p.MaxBackoff)
+}
+
+// executeWithRetry mirrors the @redis_retry decorator in Python.
+// It retries op according to the client's RetryPolicy, but only for errors
+// that IsRetryable accepts. Retrying stops as soon as ctx is done. name
+// labels the operation's retries in the client's MetricsRecorder.
+func (c *Client) executeWithRetry(ctx context.Context, name string, op func() error) error {
+	policy := c.retry.withDefaults()
+	start := time.Now()
+
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil || !IsRetryable(err) || attempt >= policy.MaxAttempts {
+			return err
+		}
+
+		wait := policy.backoff(attempt)
+		if policy.MaxElapsed > 0 && time.Since(start)+wait > policy.MaxElapsed {
+			return err
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return err
+		}
+		if c.metrics != nil {
+			c.metrics.TransportRetried(name)
+		}
+	}
+}
+
+// IsRetryable reports whether err is a transient transport failure worth
+// retrying: connection drops, network timeouts and Redis replies such as
+// LOADING or TRYAGAIN that say "try later".
+//
+// A timeout after a push was written may mean the push landed; retrying it is
+// still safe because every task carries an idempotency key (see TaskPayload)
+// that workers use to drop duplicates.
+func IsRetryable(err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return false
+	case err == redis.Nil, errors.Is(err, redis.ErrClosed):
+		return false
+	case errors.Is(err, ErrTimeout), errors.Is(err, ErrNoBrowsersAvailable), errors.Is(err, ErrInvalidResponse):
+		return false
+	}
+
+	// Redis answered: only a few replies mean "not now"
+	var replyErr redis.Error
+	if errors.As(err, &replyErr) {
+		return redis.IsLoadingError(err) ||
+			redis.IsTryAgainError(err) ||
+			redis.IsMasterDownError(err) ||
+			redis.IsClusterDownError(err) ||
+			redis.IsReadOnlyError(err) ||
+			redis.IsMaxClientsError(err) ||
+			redis.HasErrorPrefix(err, "BUSY")
+	}
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) || errors.Is(err, net.ErrClosed) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
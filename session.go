@@ -0,0 +1,80 @@
+package isoautomate
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// BrowserSession is one acquired browser, as returned by Client.Acquire.
+// It owns its init state, artifacts and lease, and carries all the action
+// methods. Sessions of the same Client share its connection pool, and a
+// session is safe for concurrent use, so one Client can drive many browsers
+// from many goroutines.
+type BrowserSession struct {
+	Session     *Session               // Active Session Data (nil once released)
+	SessionData map[string]interface{} // Metadata from Release
+	VideoURL    string
+	RecordURL   string
+	InitSent    bool // Tracks if we've sent the first command
+
+	client      *Client
+	mu          sync.Mutex         // Guards the fields above while tasks are enqueued concurrently
+	keepalive   *keepalive         // Renews the session's lease
+	profileLock *profileLockKeeper // Renews the session's profile lock, if it has a profile
+	span        trace.Span         // The session span, from Acquire (or Attach) to Release
+}
+
+// Sender is anything commands can be sent through: a *BrowserSession, or a
+// *Client acting on its default session. It is used by SendTyped.
+type Sender interface {
+	sendRaw(ctx context.Context, action string, args map[string]interface{}, timeout time.Duration) ([]byte, error)
+}
+
+var (
+	_ Sender = (*BrowserSession)(nil)
+	_ Sender = (*Client)(nil)
+)
+
+func newBrowserSession(c *Client) *BrowserSession {
+	return &BrowserSession{
+		client:      c,
+		SessionData: make(map[string]interface{}),
+	}
+}
+
+// start makes s hold a browser, replacing what a previous session left in
+// it (a released default session is reused), and tracks it.
+func (s *BrowserSession) start(sess *Session, initSent bool, k *keepalive, lock *profileLockKeeper, span trace.Span) {
+	s.mu.Lock()
+	s.Session = sess
+	s.SessionData = make(map[string]interface{})
+	s.VideoURL, s.RecordURL = "", ""
+	s.InitSent = initSent
+	s.keepalive, s.profileLock, s.span = k, lock, span
+	s.mu.Unlock()
+	s.client.track(s)
+}
+
+// Client returns the client the session was acquired from.
+func (s *BrowserSession) Client() *Client {
+	return s.client
+}
+
+// Active reports whether the session holds a browser, i.e. it was acquired
+// and not released yet.
+func (s *BrowserSession) Active() bool {
+	return s.current() != nil
+}
+
+// current returns the session data, or nil if the session is not acquired.
+func (s *BrowserSession) current() *Session {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Session
+}
@@ -0,0 +1,223 @@
+package isoautomate
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DefaultShutdownGrace bounds how long a shutdown waits for the sessions to
+// be released, video and record finalization included.
+const DefaultShutdownGrace = 60 * time.Second
+
+// ShutdownOptions configures a ShutdownManager.
+type ShutdownOptions struct {
+	// GracePeriod bounds the release of all sessions (default
+	// DefaultShutdownGrace). Sessions still not released by then are
+	// reported as failures; their leases run out and a reaper reclaims them.
+	GracePeriod time.Duration
+
+	// Signals to trap (default SIGINT and SIGTERM).
+	Signals []os.Signal
+
+	// OnShutdown, if set, is called once the sessions are released with
+	// nil or a *ReleaseError. Failures are also logged to the client's
+	// Logger.
+	OnShutdown func(err error)
+
+	// NoExit keeps the process running after a trapped signal. By default it
+	// exits with status 128+signal, as if the signal had not been trapped.
+	// Any manager that traps the signal can keep the process running. The
+	// managers that shut down then stop trapping signals, as if Stop had
+	// been called, so a second signal gets the default handling; call
+	// HandleShutdown again to cover sessions acquired afterwards.
+	NoExit bool
+}
+
+// ShutdownManager releases every session of a client when the process is
+// told to stop, so browsers are not left busy until their leases expire.
+// Deferred Release calls do not run on os.Exit, log.Fatal or an unhandled
+// signal; the manager covers signals, and Exit covers the rest. The managers
+// of all clients in a process shut down together: a signal (or an Exit)
+// releases the sessions of every client with a manager before the process
+// exits once.
+type ShutdownManager struct {
+	c    *Client
+	opts ShutdownOptions
+
+	sigs chan os.Signal
+	stop chan struct{}
+
+	once     sync.Once
+	err      error
+	stopOnce sync.Once
+}
+
+// HandleShutdown starts trapping opts.Signals. On the first signal every
+// session of the client, and of every other client whose manager traps the
+// signal, is released concurrently within the grace period, then the
+// process exits (unless one of those managers sets NoExit). Call Stop to go
+// back to the default signal handling.
+func (c *Client) HandleShutdown(opts ShutdownOptions) *ShutdownManager {
+	if opts.GracePeriod <= 0 {
+		opts.GracePeriod = DefaultShutdownGrace
+	}
+	if len(opts.Signals) == 0 {
+		opts.Signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+
+	m := &ShutdownManager{
+		c:    c,
+		opts: opts,
+		sigs: make(chan os.Signal, 1),
+		stop: make(chan struct{}),
+	}
+	shutdowns.join(m)
+	signal.Notify(m.sigs, opts.Signals...)
+	go m.wait()
+	return m
+}
+
+func (m *ShutdownManager) wait() {
+	select {
+	case <-m.stop:
+		return
+	case sig := <-m.sigs:
+		if shutdowns.signalled(sig) {
+			code := 1
+			if s, ok := sig.(syscall.Signal); ok {
+				code = 128 + int(s)
+			}
+			os.Exit(code)
+		}
+	}
+}
+
+// Shutdown releases every session of the client concurrently, finalizing
+// video and recordings, within the grace period. It runs once; later calls
+// (and a signal arriving afterwards) return the first result, nil or a
+// *ReleaseError naming the sessions that could not be released.
+func (m *ShutdownManager) Shutdown() error {
+	m.once.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), m.opts.GracePeriod)
+		defer cancel()
+
+		m.err = m.c.ReleaseAll(ctx)
+		if m.err != nil {
+			m.c.logger.Error("shutdown could not release every session", slog.String("error", m.err.Error()))
+		}
+		if m.opts.OnShutdown != nil {
+			m.opts.OnShutdown(m.err)
+		}
+	})
+	return m.err
+}
+
+// Exit runs Shutdown, and that of every other manager in the process, and
+// exits the process with code. Use it in place of os.Exit and log.Fatal
+// while sessions may be held.
+func (m *ShutdownManager) Exit(code int) {
+	shutdowns.exiting(m)
+	os.Exit(code)
+}
+
+// Stop stops trapping signals. It does not wait for a shutdown already
+// under way, which still completes (and exits, unless NoExit is set), so it
+// is safe to call from OnShutdown.
+func (m *ShutdownManager) Stop() {
+	m.stopOnce.Do(func() {
+		signal.Stop(m.sigs)
+		shutdowns.leave(m)
+		close(m.stop)
+	})
+}
+
+// shutdowns is the process's registry of running managers. Every manager
+// gets the signals it traps on its own channel (so Stop can give them back
+// precisely), but the shutdown and the exit are done here, once for all.
+var shutdowns = &shutdownRegistry{managers: make(map[*ShutdownManager]struct{})}
+
+type shutdownRegistry struct {
+	run sync.Mutex // Held for a whole shutdown; managers woken by the same signal wait for the first
+
+	mu       sync.Mutex
+	managers map[*ShutdownManager]struct{}
+}
+
+func (r *shutdownRegistry) join(m *ShutdownManager) {
+	r.mu.Lock()
+	r.managers[m] = struct{}{}
+	r.mu.Unlock()
+}
+
+func (r *shutdownRegistry) leave(m *ShutdownManager) {
+	r.mu.Lock()
+	delete(r.managers, m)
+	r.mu.Unlock()
+}
+
+// signalled shuts down every manager that traps sig and reports whether the
+// process should exit: some manager trapped it and none sets NoExit. If
+// not, those managers are stopped, since they have nothing left to do.
+func (r *shutdownRegistry) signalled(sig os.Signal) bool {
+	r.run.Lock()
+	defer r.run.Unlock()
+
+	var ms []*ShutdownManager
+	exit := false
+	r.mu.Lock()
+	for m := range r.managers {
+		if slices.Contains(m.opts.Signals, sig) {
+			ms = append(ms, m)
+			exit = true
+		}
+	}
+	r.mu.Unlock()
+	for _, m := range ms {
+		exit = exit && !m.opts.NoExit
+		m.c.logger.Info("shutdown signal received, releasing sessions",
+			slog.String("signal", sig.String()),
+			slog.Int("sessions", len(m.c.Sessions())))
+	}
+	shutdownAll(ms)
+	if !exit {
+		for _, m := range ms {
+			m.Stop()
+		}
+	}
+	return exit
+}
+
+// exiting shuts down m and every running manager before an Exit.
+func (r *shutdownRegistry) exiting(m *ShutdownManager) {
+	r.run.Lock()
+	defer r.run.Unlock()
+
+	r.mu.Lock()
+	ms := []*ShutdownManager{m}
+	for other := range r.managers {
+		if other != m {
+			ms = append(ms, other)
+		}
+	}
+	r.mu.Unlock()
+	shutdownAll(ms)
+}
+
+// shutdownAll runs the managers' Shutdown concurrently and waits for them.
+func shutdownAll(ms []*ShutdownManager) {
+	var wg sync.WaitGroup
+	for _, m := range ms {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = m.Shutdown()
+		}()
+	}
+	wg.Wait()
+}
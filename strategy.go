@@ -0,0 +1,29 @@
+package isoautomate
+
+// Strategy decides in which order Acquire tries the workers that pass the
+// worker selection. The order is computed inside the acquire script, so the
+// choice and the reservation stay one atomic step.
+type Strategy string
+
+const (
+	// StrategyRandom tries workers in random order (the default).
+	StrategyRandom Strategy = "random"
+	// StrategyLeastBusy prefers the worker with the fewest busy browsers of
+	// the requested type.
+	StrategyLeastBusy Strategy = "least_busy"
+	// StrategyRoundRobin rotates the first worker tried on every acquire of
+	// the browser type, across all clients.
+	StrategyRoundRobin Strategy = "round_robin"
+	// StrategyWeighted picks workers at random in proportion to their
+	// capacity: the "capacity" field of ISOAUTOMATE:<worker>:labels, or the
+	// worker's number of browsers of the type if it has none.
+	StrategyWeighted Strategy = "weighted"
+)
+
+func (s Strategy) valid() bool {
+	switch s {
+	case StrategyRandom, StrategyLeastBusy, StrategyRoundRobin, StrategyWeighted:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package isoautomate
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// tlsSettings are the resolved TLS fields of a Config.
+type tlsSettings struct {
+	caFile     string
+	certFile   string
+	keyFile    string
+	serverName string
+	minVersion string
+	insecure   bool
+}
+
+// configured reports whether any setting asks for TLS by itself.
+func (s tlsSettings) configured() bool {
+	return s.caFile != "" || s.certFile != ""
+}
+
+// tlsVersions maps the accepted RedisTLSMinVersion values.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// build returns the tls.Config for the Redis connection. The server
+// certificate is verified (against the CA file, or the system roots) unless
+// insecure mode was asked for explicitly.
+func (s tlsSettings) build() (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         s.serverName,
+		InsecureSkipVerify: s.insecure,
+	}
+
+	if s.minVersion != "" {
+		v, ok := tlsVersions[s.minVersion]
+		if !ok {
+			return nil, fmt.Errorf("unknown TLS version %q (use 1.0, 1.1, 1.2 or 1.3)", s.minVersion)
+		}
+		cfg.MinVersion = v
+	}
+
+	if s.caFile != "" {
+		pem, err := os.ReadFile(s.caFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", s.caFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if s.certFile != "" || s.keyFile != "" {
+		if s.certFile == "" || s.keyFile == "" {
+			return nil, fmt.Errorf("a client certificate needs both a cert file and a key file")
+		}
+		cert, err := tls.LoadX509KeyPair(s.certFile, s.keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
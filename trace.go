@@ -0,0 +1,129 @@
+package isoautomate
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation scope of the SDK's spans.
+const tracerName = "github.com/isoAutomate/isoautomate-go"
+
+// traceContext is the propagator for TaskPayload.TraceContext: workers
+// continue the trace from the W3C "traceparent" and "tracestate" entries.
+var traceContext = propagation.TraceContext{}
+
+// SetTracerProvider makes the client trace to tp; nil goes back to the
+// global provider (otel.GetTracerProvider), which is the default.
+//
+// A session is one "isoautomate.session" span from Acquire to Release, with
+// "isoautomate.acquire" and "isoautomate.release" children. Every task is a
+// client span named after its action, under the span of the ctx it was sent
+// with, or under the session span if ctx has none. Its trace context is sent
+// to the worker in TaskPayload.TraceContext. Set it before acquiring
+// sessions.
+func (c *Client) SetTracerProvider(tp trace.TracerProvider) {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	c.tracer = tp.Tracer(tracerName)
+}
+
+// Span attributes
+const (
+	attrAction      = attribute.Key("isoautomate.action")
+	attrTaskID      = attribute.Key("isoautomate.task_id")
+	attrWorker      = attribute.Key("isoautomate.worker")
+	attrBrowserID   = attribute.Key("isoautomate.browser_id")
+	attrBrowserType = attribute.Key("isoautomate.browser_type")
+	attrProfile     = attribute.Key("isoautomate.profile")
+	attrQueueWait   = attribute.Key("isoautomate.queue_wait_ms")
+	attrPayloadSize = attribute.Key("isoautomate.payload_size")
+	attrResultSize  = attribute.Key("isoautomate.response_size")
+	attrOutcome     = attribute.Key("isoautomate.outcome")
+)
+
+// startTaskSpanLocked starts the span of a task sent through s, from the
+// time the task was sent. Without a span in ctx the task is traced under
+// the session. s.mu must be held.
+func (s *BrowserSession) startTaskSpanLocked(ctx context.Context, task *pendingTask) context.Context {
+	if !trace.SpanContextFromContext(ctx).IsValid() && s.span != nil {
+		ctx = trace.ContextWithSpan(ctx, s.span)
+	}
+	ctx, task.span = s.client.tracer.Start(ctx, task.Action,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(task.started),
+		trace.WithAttributes(taskSpanAttrs(task)...))
+	return ctx
+}
+
+// taskSpanAttrs describes a task on its span.
+func taskSpanAttrs(task *pendingTask) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attrAction.String(task.Action),
+		attrTaskID.String(task.ID),
+		attrWorker.String(task.Worker),
+		attrBrowserID.String(task.BrowserID),
+	}
+}
+
+// injectTrace records ctx's trace context in the payload for the worker.
+func injectTrace(ctx context.Context, payload *TaskPayload) {
+	carrier := propagation.MapCarrier{}
+	traceContext.Inject(ctx, carrier)
+	if len(carrier) > 0 {
+		payload.TraceContext = carrier
+	}
+}
+
+// enqueuedTaskSpan annotates the span in ctx once its task is on the
+// worker's queue. The queue wait is the time spent getting it there (the
+// session's send order and the Redis push, retries included); the time it
+// then waits on the worker shows as the gap before the worker's own span.
+func enqueuedTaskSpan(ctx context.Context, task *pendingTask, payloadSize int) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+	span.SetAttributes(
+		attrPayloadSize.Int(payloadSize),
+		attrQueueWait.Float64(float64(time.Since(task.started))/float64(time.Millisecond)),
+	)
+	span.AddEvent("enqueued")
+}
+
+// endTaskSpan ends a task's span with its outcome. Assertion failures are
+// answers, not errors, so only transport and action failures mark the span
+// as failed.
+func endTaskSpan(span trace.Span, outcome, message string, resultSize int) {
+	span.SetAttributes(attrOutcome.String(outcome), attrResultSize.Int(resultSize))
+	if outcome != OutcomeOK && outcome != OutcomeAssertionFailed {
+		span.SetStatus(codes.Error, message)
+	}
+	span.End()
+}
+
+// sessionSpanAttrs describes a session on its spans.
+func sessionSpanAttrs(sess *Session) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attrWorker.String(sess.WorkerName),
+		attrBrowserID.String(sess.BrowserID),
+		attrBrowserType.String(sess.BrowserType),
+	}
+	if sess.ProfileName != "" {
+		attrs = append(attrs, attrProfile.String(sess.ProfileName))
+	}
+	return attrs
+}
+
+// failSpan marks span as failed with err and ends it.
+func failSpan(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	span.End()
+}
@@ -1,136 +1,395 @@
 package isoautomate
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
+	"log/slog"
+	"slices"
 	"time"
 
-	"github.com/google/uuid"
-	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // DefaultRPCWait is the default time to wait for a worker response (60s)
 const DefaultRPCWait = 60 * time.Second
 
+// Transport moves tasks and results between the SDK and the workers and
+// keeps the fleet's free/busy browser accounting. RedisTransport talks to a
+// real isoFleet; MemoryTransport keeps everything in process.
+//
+// Implementations must be safe for concurrent use.
+type Transport interface {
+	// Enqueue appends a serialized TaskPayload to the worker's task queue.
+	Enqueue(ctx context.Context, worker string, task []byte) error
+
+	// Await blocks until a result is delivered to resultKey and returns it.
+	// It returns ErrTimeout if nothing arrives within timeout, and ctx's
+	// error as soon as ctx is done.
+	Await(ctx context.Context, resultKey string, timeout time.Duration) ([]byte, error)
+
+	// CancelTask tells the worker to skip or abort a task nobody waits for.
+	CancelTask(ctx context.Context, taskID string) error
+
+	// AcquireBrowser atomically moves one free browser to the busy set and
+	// leases it to the requester, or returns ErrNoBrowsersAvailable.
+	AcquireBrowser(ctx context.Context, req AcquireRequest) (Lease, error)
+
+	// ReleaseBrowser atomically moves a busy browser back to the free set and
+	// drops its lease. Releasing a browser that is not busy is not an error.
+	ReleaseBrowser(ctx context.Context, worker, browserType, browserID string) error
+
+	// ReleaseLease is ReleaseBrowser for a caller that must still hold the
+	// lease: it returns ErrLeaseLost, and changes nothing, if lease.Token
+	// no longer holds it.
+	ReleaseLease(ctx context.Context, lease Lease) error
+
+	// BrowserBusy reports whether a browser is in its worker's busy set.
+	BrowserBusy(ctx context.Context, worker, browserType, browserID string) (bool, error)
+
+	// BrowserCounts reports how many browsers of each type every registered
+	// worker has free and busy, sorted by worker and browser type. Types no
+	// acquire has asked for yet may be missing unless a worker announced them.
+	BrowserCounts(ctx context.Context) ([]BrowserCount, error)
+
+	// RenewLease pushes the lease's expiry to now+ttl and returns it, or
+	// returns ErrLeaseLost if the lease expired or is held by someone else.
+	RenewLease(ctx context.Context, lease Lease, ttl time.Duration) (time.Time, error)
+
+	// DropLease forgets a lease the caller holds, leaving the browser sets
+	// alone (the worker moves the browser back on release_browser).
+	DropLease(ctx context.Context, lease Lease) error
+
+	// ReapExpiredLeases atomically takes over every expired lease whose
+	// browser is still busy: the lease passes to token for hold, so the
+	// browser stays busy until the caller frees it with ReleaseLease. It
+	// reports the reclaimed leases as they were when they expired (without
+	// their token). Expired leases of browsers no longer busy are dropped.
+	ReapExpiredLeases(ctx context.Context, token string, hold time.Duration) ([]Lease, error)
+
+	// JoinQueue appends req.QueueTicket, with req's worker selection, to the
+	// browser type's FIFO wait queue (or keeps it in place if it is already
+	// queued) and reports its position. A ticket that is not refreshed by
+	// another JoinQueue within ttl is dropped, so a crashed waiter cannot
+	// block the queue.
+	JoinQueue(ctx context.Context, req AcquireRequest, ttl time.Duration) (QueueStatus, error)
+
+	// LeaveQueue removes ticket from the browser type's wait queue.
+	LeaveQueue(ctx context.Context, browserType, ticket string) error
+
+	// WatchReleases returns a channel that receives a signal whenever a
+	// browser of the type may have become free, until ctx is done.
+	WatchReleases(ctx context.Context, browserType string) (<-chan struct{}, error)
+
+	// NotifyReleased signals the type's watchers that a browser was freed
+	// outside the transport (by the worker, on release_browser).
+	NotifyReleased(ctx context.Context, browserType string) error
+
+	// The transport also keeps the profile registry.
+	ProfileStore
+
+	// Ping checks that the backend is reachable.
+	Ping(ctx context.Context) error
+
+	// Close releases the transport's resources.
+	Close() error
+}
+
+// BrowserCount is the size of one worker's free and busy sets for one
+// browser type.
+type BrowserCount struct {
+	Worker      string
+	BrowserType string
+	Free        int
+	Busy        int
+}
+
+// sortBrowserCounts orders counts by worker, then browser type.
+func sortBrowserCounts(counts []BrowserCount) {
+	slices.SortFunc(counts, func(a, b BrowserCount) int {
+		return cmp.Or(cmp.Compare(a.Worker, b.Worker), cmp.Compare(a.BrowserType, b.BrowserType))
+	})
+}
+
 // Send transmits a generic command to the browser worker via Redis.
 // It matches the Python _send method.
-func (c *Client) Send(action string, args map[string]interface{}) (map[string]interface{}, error) {
-	return c.SendWithTimeout(action, args, DefaultRPCWait)
+func (s *BrowserSession) Send(action string, args map[string]interface{}) (map[string]interface{}, error) {
+	return s.SendWithTimeoutContext(context.Background(), action, args, DefaultRPCWait)
+}
+
+// SendContext is like Send but honours ctx. Cancelling ctx stops waiting for
+// the worker and tells it to abandon the task.
+func (s *BrowserSession) SendContext(ctx context.Context, action string, args map[string]interface{}) (map[string]interface{}, error) {
+	return s.SendWithTimeoutContext(ctx, action, args, DefaultRPCWait)
 }
 
 // SendWithTimeout allows specifying a custom timeout (e.g., for release or heavy tasks).
-func (c *Client) SendWithTimeout(action string, args map[string]interface{}, timeout time.Duration) (map[string]interface{}, error) {
-	if c.Session == nil {
-		return nil, NewBrowserError("Cannot perform action '%s': Browser session not acquired.", action)
+func (s *BrowserSession) SendWithTimeout(action string, args map[string]interface{}, timeout time.Duration) (map[string]interface{}, error) {
+	return s.SendWithTimeoutContext(context.Background(), action, args, timeout)
+}
+
+// SendWithTimeoutContext is like SendWithTimeout but honours ctx. Whichever of
+// ctx and timeout expires first ends the wait.
+//
+// A response with status "error" is returned together with an *ActionError.
+func (s *BrowserSession) SendWithTimeoutContext(ctx context.Context, action string, args map[string]interface{}, timeout time.Duration) (map[string]interface{}, error) {
+	raw, err := s.sendRaw(ctx, action, args, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return decodeMap(action, raw)
+}
+
+// decodeMap unmarshals a raw worker response into a map, returning an
+// *ActionError alongside the map for status "error" responses.
+func decodeMap(action string, raw []byte) (map[string]interface{}, error) {
+	var resp map[string]interface{}
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return nil, newError(ErrInvalidResponse, err, "Failed to parse worker response: %v", err)
+	}
+	return resp, actionError(action, resp)
+}
+
+// SendTyped transmits a command like SendContext and decodes the worker
+// response into T. A response with status "error" is returned as an error,
+// together with whatever was decoded.
+func SendTyped[T any](ctx context.Context, s Sender, action string, args map[string]interface{}) (T, error) {
+	return SendTypedWithTimeout[T](ctx, s, action, args, DefaultRPCWait)
+}
+
+// SendTypedWithTimeout is SendTyped with a custom timeout.
+func SendTypedWithTimeout[T any](ctx context.Context, s Sender, action string, args map[string]interface{}, timeout time.Duration) (T, error) {
+	var out T
+	raw, err := s.sendRaw(ctx, action, args, timeout)
+	if err != nil {
+		return out, err
+	}
+	return decodeResponse[T](action, raw)
+}
+
+// decodeResponse unmarshals a raw worker response into T, turning a
+// status "error" envelope into a Go error.
+func decodeResponse[T any](action string, raw []byte) (T, error) {
+	var out T
+
+	var envelope map[string]interface{}
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		return out, newError(ErrInvalidResponse, err, "Failed to parse worker response: %v", err)
+	}
+	if err := actionError(action, envelope); err != nil {
+		// Best effort: the error payload may not match T at all.
+		_ = json.Unmarshal(raw, &out)
+		return out, err
+	}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return out, newError(ErrInvalidResponse, err, "Failed to decode '%s' response: %v", action, err)
+	}
+	return out, nil
+}
+
+// actionError returns an *ActionError if the worker response reports
+// status "error", nil otherwise.
+func actionError(action string, resp map[string]interface{}) error {
+	if status, _ := resp["status"].(string); status != "error" {
+		return nil
+	}
+	msg, _ := resp["error"].(string)
+	if msg == "" {
+		msg = "unknown error"
+	}
+	return &ActionError{Action: action, Message: msg, Response: resp}
+}
+
+// AcquireRequest describes the browser a client wants to reserve.
+type AcquireRequest struct {
+	BrowserType string
+	LeaseToken  string        // Identifies the new lease's holder
+	LeaseTTL    time.Duration // How long the lease lasts without renewal
+
+	// QueueTicket is set by waiting acquires. A free browser goes to the
+	// first queued waiter whose worker selection allows its worker; others
+	// may only take browsers on workers no waiter ahead of them can use. A
+	// successful acquire removes the ticket from the queue.
+	QueueTicket string
+
+	// Worker selection; see AcquireOptions.
+	ProfileID string            // Recorded as the profile's last worker on success
+	Workers   []string          // Allowlist; empty allows every worker
+	Exclude   []string          // Denylist
+	Labels    map[string]string // Required worker labels
+	Affinity  string            // Profile ID whose last worker is tried first
+	Strategy  Strategy          // Order in which the selected workers are tried
+}
+
+// sendRaw enqueues a task for the session's worker and returns the raw JSON
+// response once it arrives.
+func (s *BrowserSession) sendRaw(ctx context.Context, action string, args map[string]interface{}, timeout time.Duration) ([]byte, error) {
+	f, err := s.sendAsync(ctx, action, args, timeout)
+	if err != nil {
+		return nil, err
+	}
+	<-f.done
+	return f.raw, f.err
+}
+
+// pendingTask is a task that has been pushed onto a worker queue.
+type pendingTask struct {
+	ID        string
+	ResultKey string
+	Action    string
+	Worker    string
+	BrowserID string
+	initTask  bool       // Carries the session init flags
+	started   time.Time  // When the task was sent, for its latency
+	span      trace.Span // Ended once the result is in
+}
+
+// finishTask reports a finished task to the logger and the metrics and ends
+// its span. ctx is the task's context, which tells a cancellation apart from
+// a failure.
+func (c *Client) finishTask(ctx context.Context, task *pendingTask, raw []byte, err error) {
+	logging := c.logger.Enabled(context.WithoutCancel(ctx), slog.LevelError)
+	if !logging && !task.span.IsRecording() && c.metrics == nil {
+		task.span.End()
+		return // Skip parsing the response when nobody looks at it
+	}
+
+	outcome, message := rpcOutcome(ctx, raw, err)
+	if logging {
+		c.logRPC(ctx, task, outcome, message)
+	}
+	if c.metrics != nil {
+		c.metrics.ObserveTask(task.Action, task.Worker, outcome, time.Since(task.started))
+	}
+	endTaskSpan(task.span, outcome, message, len(raw))
+}
+
+// enqueue builds the task payload and pushes it onto the worker's queue.
+func (s *BrowserSession) enqueue(ctx context.Context, action string, args map[string]interface{}) (*pendingTask, error) {
+	started := time.Now()
+	if err := ctx.Err(); err != nil {
+		return nil, newError(nil, err, "Action '%s' not sent: %v", action, err)
+	}
+
+	// The lock is held across the push so that the task carrying the init
+	// flags is also the first one in the worker's queue, and so Release
+	// cannot drop the session halfway.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess := s.Session
+	if sess == nil {
+		return nil, newError(ErrNotAcquired, nil, "Cannot perform action '%s': Browser session not acquired.", action)
 	}
 
 	// 1. Prepare Metadata
-	taskID := uuid.New().Hex()
-	resultKey := fmt.Sprintf("%sresult:%s", RedisPrefix, taskID)
-	queue := fmt.Sprintf("%s%s:tasks", RedisPrefix, c.Session.WorkerName)
+	task := &pendingTask{
+		ID:        newHexID(),
+		Action:    action,
+		Worker:    sess.WorkerName,
+		BrowserID: sess.BrowserID,
+		started:   started,
+	}
+	task.ResultKey = s.client.keys.result(task.ID)
+	spanCtx := s.startTaskSpanLocked(ctx, task)
 
 	// 2. Construct Payload
 	// We use the struct for safety, but we might need to marshal it carefully to match Python's flat dict
 	payload := TaskPayload{
-		TaskID:     taskID,
-		BrowserID:  c.Session.BrowserID,
-		WorkerName: c.Session.WorkerName,
-		Action:     action,
-		Args:       args,
-		ResultKey:  resultKey,
+		TaskID:         task.ID,
+		IdempotencyKey: task.ID, // A fresh ID per logical task, reused by retries
+		BrowserID:      sess.BrowserID,
+		WorkerName:     sess.WorkerName,
+		Action:         action,
+		Args:           args,
+		ResultKey:      task.ResultKey,
 	}
 
 	// 3. Handle Init Flags (Sent only on the first command)
-	if !c.InitSent {
-		if c.Session.Video {
+	if !s.InitSent {
+		task.initTask = true
+		if sess.Video {
 			payload.Video = true
 		}
-		if c.Session.Record {
+		if sess.Record {
 			payload.Record = true
 		}
-		if c.Session.ProfileID != "" {
-			payload.ProfileID = c.Session.ProfileID
-			payload.BrowserType = c.Session.BrowserType
+		if sess.ProfileID != "" {
+			payload.ProfileID = sess.ProfileID
+			payload.BrowserType = sess.BrowserType
+			payload.CloneFrom = sess.CloneFrom
 		}
 	}
 
-	// Serialize Payload
+	// 4. Send to the worker queue (RPUSH) with Retry
+	if err := s.client.push(spanCtx, task, payload); err != nil {
+		s.client.finishTask(ctx, task, nil, err)
+		return nil, err
+	}
+
+	// Later tasks must not repeat the flags; sendAsync resets this if the
+	// init task never gets an answer.
+	s.InitSent = true
+	return task, nil
+}
+
+// push serializes a payload, with the trace context of ctx, and appends it
+// to the task's worker queue, retrying transient failures.
+func (c *Client) push(ctx context.Context, task *pendingTask, payload TaskPayload) error {
+	injectTrace(ctx, &payload)
 	data, err := json.Marshal(payload)
 	if err != nil {
-		return nil, NewBrowserError("Failed to serialize task payload: %v", err)
+		return newError(nil, err, "Failed to serialize task payload: %v", err)
 	}
 
-	// 4. Send to Redis (RPUSH) with Retry
-	err = c.executeWithRetry(func() error {
-		return c.R.RPush(c.ctx, queue, data).Err()
+	err = c.executeWithRetry(ctx, "enqueue", func() error {
+		return c.transport.Enqueue(ctx, task.Worker, data)
 	})
 	if err != nil {
-		return nil, err
+		if ctx.Err() != nil {
+			return newError(nil, ctx.Err(), "Action '%s' not sent: %v", payload.Action, ctx.Err())
+		}
+		return newError(nil, err, "Redis RPC Error: %v", err)
 	}
+	enqueuedTaskSpan(ctx, task, len(data))
+	return nil
+}
 
+// collect waits for the result of an enqueued task.
+func (c *Client) collect(ctx context.Context, task *pendingTask, timeout time.Duration) ([]byte, error) {
 	// 5. Wait for Result (BLPOP) with Retry
-	// We use the context for timeout to ensure we don't hang forever
-	var resultRaw []string
-
-	ctx, cancel := context.WithTimeout(c.ctx, timeout)
-	defer cancel()
-
-	err = c.executeWithRetry(func() error {
-		var rErr error
-		resultRaw, rErr = c.R.BLPop(ctx, timeout, resultKey).Result()
-		return rErr
+	// The timeout bounds the wait itself; ctx can end the wait earlier.
+	var result []byte
+	err := c.executeWithRetry(ctx, "await", func() error {
+		var aErr error
+		result, aErr = c.transport.Await(ctx, task.ResultKey, timeout)
+		return aErr
 	})
-
+	c.finishTask(ctx, task, result, err)
 	if err != nil {
-		if err == redis.Nil || err == context.DeadlineExceeded {
-			return nil, NewBrowserError("Timeout waiting for worker response")
+		// The caller gave up: make sure the worker does not run the task later.
+		if ctx.Err() != nil {
+			c.abandonTask(task.ID)
+			return nil, newError(nil, ctx.Err(), "Action '%s' cancelled: %v", task.Action, ctx.Err())
 		}
-		return nil, NewBrowserError("Redis RPC Error: %v", err)
-	}
-
-	// 6. Parse Response
-	if len(resultRaw) < 2 {
-		return nil, NewBrowserError("Invalid response from Redis")
-	}
-
-	var resp map[string]interface{}
-	if err := json.Unmarshal([]byte(resultRaw[1]), &resp); err != nil {
-		return nil, NewBrowserError("Failed to parse worker response: %v", err)
+		if errors.Is(err, ErrTimeout) {
+			return nil, newError(ErrTimeout, err, "Timeout waiting for worker response")
+		}
+		if errors.Is(err, ErrInvalidResponse) {
+			return nil, newError(ErrInvalidResponse, err, "Invalid response from Redis")
+		}
+		return nil, newError(nil, err, "Redis RPC Error: %v", err)
 	}
-
-	// Mark init as sent if successful
-	c.InitSent = true
-
-	// Cleanup result key (optional, but good hygiene if BLPOP didn't remove it, though BLPOP usually does pop it)
-	// Redis BLPOP removes the item from the list. The key itself is a list.
-	// We don't need to delete the list key explicitly if it's empty, Redis handles that.
-
-	return resp, nil
+	return result, nil
 }
 
-// executeWithRetry mirrors the @redis_retry decorator in Python.
-// It retries the operation up to 3 times with exponential backoff.
-func (c *Client) executeWithRetry(op func() error) error {
-	maxAttempts := 3
-	backoffFactor := 0.2
-	attempt := 0
-
-	for {
-		err := op()
-		if err == nil {
-			return nil
-		}
+// abandonTask marks a task as cancelled so the worker skips it (or stops it)
+// and does not leave an orphaned result behind. It runs on its own short
+// context because the caller's context is already done.
+func (c *Client) abandonTask(taskID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 
-		// Check if it's a Redis connection/timeout error
-		// In Go, we check the error type or content
-		// Ideally we only retry on network errors, but for simplicity we retry on most Redis errors except explicit logical ones
-		if err != redis.Nil && attempt < maxAttempts {
-			attempt++
-			sleepTime := time.Duration(float64(time.Second) * backoffFactor * (1 << (attempt - 1))) // 0.2s, 0.4s, 0.8s
-			time.Sleep(sleepTime)
-			continue
-		}
-		return err
-	}
+	_ = c.transport.CancelTask(ctx, taskID)
 }
@@ -1,5 +1,7 @@
 package isoautomate
 
+import "encoding/json"
+
 // Session represents the active browser session
 type Session struct {
 	BrowserID   string `json:"browser_id"`
@@ -8,20 +10,32 @@ type Session struct {
 	Video       bool   `json:"video"`
 	Record      bool   `json:"record"`
 	ProfileID   string `json:"profile_id,omitempty"`
+	ProfileName string `json:"profile_name,omitempty"`
+	CloneFrom   string `json:"clone_from,omitempty"` // Profile ID to seed a new clone from
 }
 
 // TaskPayload represents the JSON sent TO Redis (RPUSH)
+//
+// IdempotencyKey is identical across every retry of the same push. A push
+// that timed out may still have reached Redis, so workers must drop a task
+// whose key they have already seen.
 type TaskPayload struct {
-	TaskID      string                 `json:"task_id"`
-	BrowserID   string                 `json:"browser_id"`
-	WorkerName  string                 `json:"worker_name"`
-	Action      string                 `json:"action"`
-	Args        map[string]interface{} `json:"args"`
-	ResultKey   string                 `json:"result_key"`
-	Video       bool                   `json:"video,omitempty"`
-	Record      bool                   `json:"record,omitempty"`
-	ProfileID   string                 `json:"profile_id,omitempty"`
-	BrowserType string                 `json:"browser_type,omitempty"`
+	TaskID         string                 `json:"task_id"`
+	IdempotencyKey string                 `json:"idempotency_key,omitempty"`
+	BrowserID      string                 `json:"browser_id"`
+	WorkerName     string                 `json:"worker_name"`
+	Action         string                 `json:"action"`
+	Args           map[string]interface{} `json:"args"`
+	ResultKey      string                 `json:"result_key"`
+	Video          bool                   `json:"video,omitempty"`
+	Record         bool                   `json:"record,omitempty"`
+	ProfileID      string                 `json:"profile_id,omitempty"`
+	BrowserType    string                 `json:"browser_type,omitempty"`
+	CloneFrom      string                 `json:"clone_from,omitempty"` // Seed a new profile from this profile ID
+
+	// TraceContext carries the W3C "traceparent" and "tracestate" of the
+	// task's span, for the worker to continue the trace.
+	TraceContext map[string]string `json:"trace_context,omitempty"`
 }
 
 // TaskResponse represents the JSON received FROM Redis (BLPOP)
@@ -33,3 +47,107 @@ type TaskResponse struct {
 	ScreenshotBase64 string      `json:"screenshot_base64,omitempty"`
 	Data             interface{} `json:"data,omitempty"`
 }
+
+// --- Typed Results ---
+//
+// Result types embed TaskResponse so the common envelope fields (status,
+// error, artifacts) stay available next to the decoded value.
+
+// StringResult is returned by getters that yield a single string
+// (title, URL, text, attributes, storage items...).
+type StringResult struct {
+	TaskResponse
+	Value string `json:"value"`
+}
+
+// BoolResult is returned by the Is* checks.
+type BoolResult struct {
+	TaskResponse
+	Value bool `json:"value"`
+}
+
+// Rect is the position and size of an element, window or screen in CSS pixels.
+type Rect struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// RectResult is returned by GetElementRect, GetWindowRect and GetScreenRect.
+type RectResult struct {
+	TaskResponse
+	Value Rect `json:"value"`
+}
+
+// Cookie mirrors the WebDriver/CDP cookie object.
+type Cookie struct {
+	Name     string  `json:"name"`
+	Value    string  `json:"value"`
+	Domain   string  `json:"domain,omitempty"`
+	Path     string  `json:"path,omitempty"`
+	Expires  float64 `json:"expires,omitempty"`
+	HTTPOnly bool    `json:"httpOnly,omitempty"`
+	Secure   bool    `json:"secure,omitempty"`
+	SameSite string  `json:"sameSite,omitempty"`
+}
+
+// CookiesResult is returned by GetAllCookies.
+type CookiesResult struct {
+	TaskResponse
+	Cookies []Cookie `json:"cookies"`
+}
+
+// NavigationEntry is one page in the tab's history.
+type NavigationEntry struct {
+	ID             int    `json:"id"`
+	URL            string `json:"url"`
+	UserTypedURL   string `json:"userTypedURL,omitempty"`
+	Title          string `json:"title"`
+	TransitionType string `json:"transitionType,omitempty"`
+}
+
+// NavigationHistory is the tab's history with the index of the current entry.
+type NavigationHistory struct {
+	CurrentIndex int               `json:"currentIndex"`
+	Entries      []NavigationEntry `json:"entries"`
+}
+
+// NavigationHistoryResult is returned by GetNavigationHistory.
+type NavigationHistoryResult struct {
+	TaskResponse
+	Value NavigationHistory `json:"value"`
+}
+
+// PerformanceMetrics maps metric names (e.g. "JSHeapUsedSize") to values.
+type PerformanceMetrics map[string]float64
+
+// UnmarshalJSON accepts both a plain name->value object and the CDP
+// Performance.getMetrics list form ([{"name": ..., "value": ...}]).
+func (m *PerformanceMetrics) UnmarshalJSON(data []byte) error {
+	var list []struct {
+		Name  string  `json:"name"`
+		Value float64 `json:"value"`
+	}
+	if err := json.Unmarshal(data, &list); err == nil {
+		out := make(PerformanceMetrics, len(list))
+		for _, item := range list {
+			out[item.Name] = item.Value
+		}
+		*m = out
+		return nil
+	}
+
+	var obj map[string]float64
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	*m = obj
+	return nil
+}
+
+// PerformanceMetricsResult is returned by GetPerformanceMetrics.
+type PerformanceMetricsResult struct {
+	TaskResponse
+	Value PerformanceMetrics `json:"value"`
+}
@@ -2,10 +2,12 @@ package isoautomate
 
 import (
 	"encoding/base64"
+	"encoding/hex"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/google/uuid"
 	"github.com/joho/godotenv"
 )
 
@@ -23,24 +25,45 @@ func LoadEnv(customPath string) {
 	_ = godotenv.Load(cwdEnv)
 }
 
+// firstNonEmpty returns the first of values that is not empty.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// splitList splits a comma-separated list, dropping empty entries.
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
 // SaveBase64File decodes a base64 string and writes it to the output path.
 // It ensures the directory exists.
 func SaveBase64File(base64Data, outputPath string) (string, error) {
 	// Decode
 	data, err := base64.StdEncoding.DecodeString(base64Data)
 	if err != nil {
-		return "", NewBrowserError("Failed to decode base64 data: %v", err)
+		return "", newError(nil, err, "Failed to decode base64 data: %v", err)
 	}
 
 	// Ensure directory exists
 	dir := filepath.Dir(outputPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
-		return "", NewBrowserError("Failed to create directory: %v", err)
+		return "", newError(nil, err, "Failed to create directory: %v", err)
 	}
 
 	// Write file
 	if err := os.WriteFile(outputPath, data, 0644); err != nil {
-		return "", NewBrowserError("Failed to write file: %v", err)
+		return "", newError(nil, err, "Failed to write file: %v", err)
 	}
 
 	// Return absolute path
@@ -58,3 +81,9 @@ func cleanSelector(s string) string {
 	}
 	return s
 }
+
+// newHexID returns a random UUID as 32 hex characters (Python's uuid4().hex).
+func newHexID() string {
+	id := uuid.New()
+	return hex.EncodeToString(id[:])
+}